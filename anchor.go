@@ -0,0 +1,61 @@
+package fb2text
+
+/*
+ElementID is an id of the book element, see BookInfo.IDs. Element is the name
+of the element, e.g. "section", "p", or "binary", the sections of the notes
+body are "note". Body is the name of the body of the element, it is empty for
+the main body and binaries. Line is the index of the returned line the id
+points to(see BookInfo.Anchors) or -1 if the element has no returned lines,
+e.g. a binary or an element of a body that is not selected
+*/
+type ElementID struct {
+	ID      string `json:"id"`
+	Element string `json:"element"`
+	Body    string `json:"body"`
+	Line    int    `json:"line"`
+}
+
+// indexID adds the id of the element to the index of the book ids
+func (p *parser) indexID(name, id, body string) {
+	if id == "" {
+		return
+	}
+	if name == "section" && body == notesBody && len(p.tags) == 2 {
+		name = "note"
+	}
+	p.info.IDs = append(p.info.IDs, ElementID{ID: id, Element: name, Body: body, Line: -1})
+}
+
+// resolveIDs sets the lines of the indexed ids when all lines are parsed
+func (p *parser) resolveIDs() {
+	for i, id := range p.info.IDs {
+		if idx, ok := p.info.Anchors[id.ID]; ok && id.Element != "binary" {
+			p.info.IDs[i].Line = idx
+		}
+	}
+}
+
+// anchorStart remembers the id of the element of the book text, if any
+func (p *parser) anchorStart(id string) {
+	if id != "" {
+		p.anchorIDs = append(p.anchorIDs, id)
+	}
+}
+
+// addAnchors points the ids of the started elements to the line idx
+func (p *parser) addAnchors(idx int) {
+	for _, id := range p.anchorIDs {
+		p.setAnchor(id, idx)
+	}
+	p.anchorIDs = p.anchorIDs[:0]
+}
+
+// setAnchor points the id to the line idx, the first line of an id is kept
+func (p *parser) setAnchor(id string, idx int) {
+	if p.info.Anchors == nil {
+		p.info.Anchors = make(map[string]int)
+	}
+	if _, ok := p.info.Anchors[id]; !ok {
+		p.info.Anchors[id] = idx
+	}
+}
@@ -0,0 +1,43 @@
+package fb2text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnchors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		anchors map[string]int
+	}{
+		{
+			name:    "notes body",
+			opts:    []Option{ParseBody()},
+			anchors: map[string]int{"ch1": 1, "n1": 10},
+		},
+		{
+			name:    "expanded notes",
+			opts:    []Option{ParseBody(), ExpandNotes()},
+			anchors: map[string]int{"ch1": 1, "n1": 6},
+		},
+		{
+			name:    "endnotes",
+			opts:    []Option{ParseBody(), EndNotes()},
+			anchors: map[string]int{"ch1": 1, "n1": 12},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, _, err := ParseBookFromReader(strings.NewReader(notesBook), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(info.Anchors, tt.anchors) {
+				t.Errorf("anchors = %v, want %v", info.Anchors, tt.anchors)
+			}
+		})
+	}
+}
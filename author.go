@@ -0,0 +1,95 @@
+package fb2text
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// NameOrder defines how Author.DisplayName combines the parts of the name
+type NameOrder int
+
+const (
+	// FirstLast is "Ivan Antonovich Efremov"
+	FirstLast NameOrder = iota
+	// LastFirst is "Efremov Ivan Antonovich"
+	LastFirst
+	// InitialsLast is "I. A. Efremov"
+	InitialsLast
+	// LastInitials is "Efremov I. A."
+	LastInitials
+)
+
+/*
+DisplayName returns the author name for display in the given order. The
+nickname is used if the author does not have first, middle, and last name
+*/
+func (a Author) DisplayName(order NameOrder) string {
+	first, middle := a.FirstName, a.MiddleName
+	if order == InitialsLast || order == LastInitials {
+		first, middle = initial(first), initial(middle)
+	}
+
+	var parts []string
+	switch order {
+	case LastFirst, LastInitials:
+		parts = []string{a.LastName, first, middle}
+	default:
+		parts = []string{first, middle, a.LastName}
+	}
+
+	if name := joinNonEmpty(parts, " "); name != "" {
+		return name
+	}
+
+	return a.Nickname
+}
+
+/*
+SortKey returns the key to sort authors on a shelf: lower case last name,
+first name, and middle name, or the nickname if the author does not have a
+name
+*/
+func (a Author) SortKey() string {
+	key := joinNonEmpty([]string{a.LastName, a.FirstName, a.MiddleName}, " ")
+	if key == "" {
+		key = a.Nickname
+	}
+
+	return strings.ToLower(key)
+}
+
+/*
+AuthorsString returns the names of all book authors in FirstLast order
+separated by commas, e.g. "Arkady Strugatsky, Boris Strugatsky"
+*/
+func (b BookInfo) AuthorsString() string {
+	names := make([]string, 0, len(b.Authors))
+	for _, a := range b.Authors {
+		names = append(names, a.DisplayName(FirstLast))
+	}
+
+	return joinNonEmpty(names, ", ")
+}
+
+// initial returns the first letter of the name with a dot or empty string
+func initial(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+
+	r, _ := utf8.DecodeRuneInString(name)
+	return string(r) + "."
+}
+
+// joinNonEmpty joins non-empty trimmed parts with the separator
+func joinNonEmpty(parts []string, sep string) string {
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+
+	return strings.Join(list, sep)
+}
@@ -0,0 +1,179 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Binary is a decoded attachment of the book, e.g. an illustration
+type Binary struct {
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+/*
+ParseBinaries decodes all binary attachments of the book fileName and
+returns them by their ids. The book text is skipped without parsing.
+Options ParseBody, SkipSystemLines, MaxLines, and WithLineFilter are ignored
+*/
+func ParseBinaries(fileName string, opts ...Option) (map[string]Binary, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return ParseBinariesFromReader(file, opts...)
+}
+
+/*
+ParseBinariesFromReader works the same way as ParseBinaries but reads the
+book from r. The stream can be a raw FB2, ZIP or GZIP archive. In case of
+error the function returns the binaries decoded so far with the error
+*/
+func ParseBinariesFromReader(r io.Reader, opts ...Option) (map[string]Binary, error) {
+	opt, err := newOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	opt.parseBody = true
+	opt.maxLines = 0
+	opt.lineFilter = nil
+
+	book, err := openBook(r, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer book.Close()
+
+	p := newParser(book, opt)
+	p.binaries = make(map[string]Binary)
+	for p.step() {
+		p.lines = p.lines[:0]
+	}
+
+	return p.binaries, p.err
+}
+
+// addBinary adds the decoded binary to the collected ones
+func (p *parser) addBinary(data []byte) {
+	if p.binaries == nil {
+		return
+	}
+
+	if _, ok := p.binaries[p.binaryID]; ok {
+		p.warn("duplicate binary %q", p.binaryID)
+		return
+	}
+	p.binaries[p.binaryID] = Binary{ContentType: p.binaryCType, Data: data}
+}
+
+/*
+skipForBinaries skips book bodies when only binaries are needed. Returns true
+if the element has been skipped
+*/
+func (p *parser) skipForBinaries(se xml.StartElement) bool {
+	if se.Name.Local != "body" {
+		return false
+	}
+
+	if err := p.decoder.Skip(); err != nil {
+		p.finish(p.classify(err))
+	}
+	return true
+}
+
+// imageExtensions are the file extensions of the common content types of binaries
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/jpg":     ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/bmp":     ".bmp",
+	"image/svg+xml": ".svg",
+	"image/tiff":    ".tif",
+}
+
+/*
+SaveImages decodes all binary attachments of the book fileName and writes
+them to the directory destDir, that is created if needed. The file names are
+made from the binary ids, the extension is added from the content type if
+the id does not have it. Returns the file names(without the directory) by
+the binary ids
+*/
+func SaveImages(fileName, destDir string, opts ...Option) (map[string]string, error) {
+	bins, err := ParseBinaries(fileName, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(bins))
+	for id := range bins {
+		ids = append(ids, id)
+	}
+	// the names of the files do not depend on the order of the map
+	sort.Strings(ids)
+
+	names := make(map[string]string, len(bins))
+	used := make(map[string]bool, len(bins))
+	for _, id := range ids {
+		bin := bins[id]
+		name := binaryFileName(id, bin.ContentType)
+		base, ext := strings.TrimSuffix(name, filepath.Ext(name)), filepath.Ext(name)
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		used[strings.ToLower(name)] = true
+
+		if err := os.WriteFile(filepath.Join(destDir, name), bin.Data, 0o644); err != nil {
+			return names, err
+		}
+		names[id] = name
+	}
+
+	return names, nil
+}
+
+// binaryFileName returns the safe file name for the binary
+func binaryFileName(id, contentType string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, id)
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		name = "image"
+	}
+
+	ext, ok := imageExtensions[strings.ToLower(contentType)]
+	if !ok {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		} else {
+			ext = ".bin"
+		}
+	}
+	if !strings.EqualFold(filepath.Ext(name), ext) &&
+		!(ext == ".jpg" && strings.EqualFold(filepath.Ext(name), ".jpeg")) {
+		name += ext
+	}
+
+	return name
+}
@@ -0,0 +1,35 @@
+package fb2text
+
+const (
+	// MainBody is the name of the main body of the book for WithBodies, the
+	// main body has no name in FB2
+	MainBody = "main"
+	// AllBodies selects all bodies of the book for WithBodies
+	AllBodies = "*"
+)
+
+// bodySelected returns true if the lines of the current body are returned
+func (p *parser) bodySelected() bool {
+	bodies := p.opt.bodies
+	if bodies == nil || bodies[AllBodies] {
+		return true
+	}
+
+	if p.body == "" {
+		return bodies[MainBody]
+	}
+
+	return bodies[p.body]
+}
+
+// bodyLine adds the line of a named body to the book information
+func (p *parser) bodyLine(line Line) {
+	if p.body == "" {
+		return
+	}
+
+	if p.info.Bodies == nil {
+		p.info.Bodies = make(map[string]Lines)
+	}
+	p.info.Bodies[p.body] = append(p.info.Bodies[p.body], line)
+}
@@ -0,0 +1,59 @@
+package fb2text
+
+// ParseResult is the final result of ParseBookChan
+type ParseResult struct {
+	Info BookInfo
+	Err  error
+}
+
+/*
+ParseBookChan parses the book fileName in a separate goroutine and sends
+every parsed line to out as soon as it is decoded. It allows to connect the
+parser to a concurrent processing pipeline without keeping the whole book in
+memory.
+
+out is closed when parsing is finished. After that the returned channel
+receives the book information and the error that stopped parsing, if any.
+If the option WithContext is set, the goroutine stops when the context is
+canceled even if nobody reads from out
+*/
+func ParseBookChan(fileName string, out chan<- string, opts ...Option) <-chan ParseResult {
+	done := make(chan ParseResult, 1)
+	opt, err := newOption(opts)
+	if err != nil {
+		close(out)
+		done <- ParseResult{Err: err}
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		defer close(out)
+
+		sc, err := OpenBook(fileName, opts...)
+		if err != nil {
+			done <- ParseResult{Err: err}
+			return
+		}
+		defer sc.Close()
+
+		for sc.Scan() {
+			if opt.ctx == nil {
+				out <- sc.Text()
+				continue
+			}
+
+			select {
+			case out <- sc.Text():
+			case <-opt.ctx.Done():
+				done <- ParseResult{Info: sc.Info(), Err: opt.ctx.Err()}
+				return
+			}
+		}
+
+		done <- ParseResult{Info: sc.Info(), Err: sc.Err()}
+	}()
+
+	return done
+}
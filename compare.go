@@ -0,0 +1,123 @@
+package fb2text
+
+import (
+	"reflect"
+	"unicode/utf8"
+)
+
+/*
+FieldDiff is a difference between two book descriptions. Field is the path
+to the field, e.g. "Title" or "PublishInfo.ISBN", A and B are the values of
+the field in the compared descriptions
+*/
+type FieldDiff struct {
+	Field string
+	A, B  any
+}
+
+/*
+atomicTypes are the structures that are compared and merged as a whole: the
+fields of a cover or a series make sense only together
+*/
+var atomicTypes = map[reflect.Type]bool{
+	reflect.TypeOf(Date{}):     true,
+	reflect.TypeOf(Cover{}):    true,
+	reflect.TypeOf(Sequence{}): true,
+	reflect.TypeOf(Author{}):   true,
+}
+
+// isNested returns true if the structure is compared and merged field by field
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !atomicTypes[t]
+}
+
+// Equal returns true if both book descriptions have the same information
+func (b BookInfo) Equal(other BookInfo) bool {
+	return len(b.Diff(other)) == 0
+}
+
+/*
+Diff compares the book descriptions field by field and returns the list of
+different fields. Nested structures, e.g. PublishInfo, are compared field by
+field too, lists, maps, dates, and covers are compared as a whole. The
+deprecated Genre field is not compared
+*/
+func (b BookInfo) Diff(other BookInfo) []FieldDiff {
+	diffs := make([]FieldDiff, 0)
+	diffStruct("", reflect.ValueOf(b), reflect.ValueOf(other), &diffs)
+
+	return diffs
+}
+
+func diffStruct(prefix string, a, b reflect.Value, diffs *[]FieldDiff) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == "Genre" && t == reflect.TypeOf(BookInfo{}) {
+			continue
+		}
+
+		name := prefix + field.Name
+		fa, fb := a.Field(i), b.Field(i)
+		if isNested(field.Type) {
+			diffStruct(name+".", fa, fb, diffs)
+			continue
+		}
+		if isEmptyValue(fa) && isEmptyValue(fb) {
+			// nil and empty lists are the same
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			*diffs = append(*diffs, FieldDiff{Field: name, A: fa.Interface(), B: fb.Interface()})
+		}
+	}
+}
+
+/*
+MergeBookInfo merges two descriptions of the same book, e.g. from
+duplicate files. The description with ISBN is preferred, if both or none
+have ISBN the one with longer annotation is preferred. Empty fields of the
+preferred description are filled from the other one, a cover or a date is
+taken as a whole. The longer annotation is always taken
+*/
+func MergeBookInfo(a, b BookInfo) BookInfo {
+	first, second := a, b
+	hasISBN := func(info BookInfo) bool { return info.PublishInfo.ISBN != "" }
+	annotation := func(info BookInfo) int { return utf8.RuneCountInString(info.Annotation) }
+	if hasISBN(b) && !hasISBN(a) ||
+		hasISBN(a) == hasISBN(b) && annotation(b) > annotation(a) {
+		first, second = b, a
+	}
+
+	merged := first
+	mergeStruct(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(second))
+	if annotation(second) > annotation(merged) {
+		merged.Annotation = second.Annotation
+		merged.AnnotationLines = second.AnnotationLines
+	}
+
+	return merged
+}
+
+// mergeStruct sets empty fields of dst to the values of src
+func mergeStruct(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		fd, fs := dst.Field(i), src.Field(i)
+		switch {
+		case isNested(fd.Type()):
+			mergeStruct(fd, fs)
+		case isEmptyValue(fd):
+			fd.Set(fs)
+		}
+	}
+}
+
+// isEmptyValue returns true if the value is zero or an empty list or map
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
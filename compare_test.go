@@ -0,0 +1,88 @@
+package fb2text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := BookInfo{
+		Title:       "Book",
+		Genre:       "sf",
+		Authors:     []Author{{FirstName: "Ivan", LastName: "Efremov"}},
+		PublishInfo: PublishInfo{ISBN: "1", Year: "1957"},
+		Cover:       Cover{ID: "a.jpg", Data: []byte{1}},
+	}
+
+	tests := []struct {
+		name   string
+		change func(*BookInfo)
+		fields []string
+	}{
+		{"equal", func(*BookInfo) {}, nil},
+		{"deprecated genre", func(b *BookInfo) { b.Genre = "det" }, nil},
+		{"nil and empty list", func(b *BookInfo) { b.Translators = []Author{} }, nil},
+		{"title", func(b *BookInfo) { b.Title = "Other" }, []string{"Title"}},
+		{"nested field", func(b *BookInfo) { b.PublishInfo.ISBN = "2" }, []string{"PublishInfo.ISBN"}},
+		{"list", func(b *BookInfo) { b.Authors = []Author{{LastName: "Efremov"}} }, []string{"Authors"}},
+		{"cover", func(b *BookInfo) { b.Cover.Data = []byte{2} }, []string{"Cover"}},
+		{"date", func(b *BookInfo) { b.Date.Value = "1957-01-01" }, []string{"Date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := a
+			tt.change(&b)
+			var fields []string
+			for _, diff := range a.Diff(b) {
+				fields = append(fields, diff.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("diff fields = %q, want %q", fields, tt.fields)
+			}
+			if a.Equal(b) != (len(tt.fields) == 0) {
+				t.Errorf("Equal() = %v, want %v", a.Equal(b), len(tt.fields) == 0)
+			}
+		})
+	}
+}
+
+func TestMergeBookInfo(t *testing.T) {
+	withISBN := BookInfo{
+		Title:       "Book",
+		Annotation:  "Short",
+		PublishInfo: PublishInfo{ISBN: "1"},
+		Cover:       Cover{ID: "a.jpg"},
+	}
+	withoutISBN := BookInfo{
+		Title:       "Other title",
+		Language:    "ru",
+		Annotation:  "A longer annotation",
+		PublishInfo: PublishInfo{Publisher: "Publisher"},
+		Cover:       Cover{ID: "b.jpg", ContentType: "image/jpeg", Data: []byte{1}},
+		Sequences:   []Sequence{{Name: "Series", Number: 1}},
+	}
+
+	want := BookInfo{
+		Title:       "Book",
+		Language:    "ru",
+		Annotation:  "A longer annotation",
+		PublishInfo: PublishInfo{ISBN: "1", Publisher: "Publisher"},
+		// the cover of the preferred book is not mixed with the other one
+		Cover:     Cover{ID: "a.jpg"},
+		Sequences: []Sequence{{Name: "Series", Number: 1}},
+	}
+	for _, merged := range []BookInfo{MergeBookInfo(withISBN, withoutISBN), MergeBookInfo(withoutISBN, withISBN)} {
+		if !reflect.DeepEqual(merged, want) {
+			t.Errorf("merged = %+v, want %+v", merged, want)
+		}
+	}
+
+	// without ISBN the longer annotation wins, the empty cover is filled
+	a := BookInfo{Title: "A", Annotation: "Long annotation"}
+	b := BookInfo{Title: "B", Annotation: "Short", Cover: Cover{ID: "b.jpg", Data: []byte{1}}}
+	merged := MergeBookInfo(b, a)
+	if merged.Title != "A" || merged.Cover.ID != "b.jpg" || merged.Cover.Data == nil {
+		t.Errorf("merged = %+v, want title A and cover b.jpg", merged)
+	}
+}
@@ -0,0 +1,142 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"slices"
+)
+
+/*
+Cover is an image from the book coverpage. ID is the identifier of the
+binary element with the image, Data is the decoded image content
+*/
+type Cover struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+/*
+ExtractCover reads the cover image of the book fileName. It is much faster
+than ParseBook with ParseBody: the book text is skipped without parsing and
+only the binaries referenced by the coverpage are decoded. If coverpage has
+several images the first one is returned. Returns ErrNoCover if the book
+does not have a cover. Options ParseBody, SkipSystemLines, MaxLines, and
+WithLineFilter are ignored
+*/
+func ExtractCover(fileName string, opts ...Option) (Cover, error) {
+	covers, err := ExtractCovers(fileName, opts...)
+	if err != nil {
+		return Cover{}, err
+	}
+
+	return covers[0], nil
+}
+
+/*
+ExtractCoverFromReader works the same way as ExtractCover but reads the
+book from r. The stream can be a raw FB2, ZIP or GZIP archive
+*/
+func ExtractCoverFromReader(r io.Reader, opts ...Option) (Cover, error) {
+	covers, err := ExtractCoversFromReader(r, opts...)
+	if err != nil {
+		return Cover{}, err
+	}
+
+	return covers[0], nil
+}
+
+/*
+ExtractCovers works the same way as ExtractCover but returns all images of
+coverpage, e.g. the front and the back cover, in the order of coverpage
+*/
+func ExtractCovers(fileName string, opts ...Option) ([]Cover, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return ExtractCoversFromReader(file, opts...)
+}
+
+/*
+ExtractCoversFromReader works the same way as ExtractCovers but reads the
+book from r. The stream can be a raw FB2, ZIP or GZIP archive
+*/
+func ExtractCoversFromReader(r io.Reader, opts ...Option) ([]Cover, error) {
+	opt, err := newOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	opt.parseBody = true
+	opt.maxLines = 0
+	opt.lineFilter = nil
+
+	book, err := openBook(r, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer book.Close()
+
+	p := newParser(book, opt)
+	p.coverOnly = true
+	for p.step() {
+		p.lines = p.lines[:0]
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	if len(p.info.Covers) == 0 {
+		return nil, ErrNoCover
+	}
+
+	return p.info.Covers, nil
+}
+
+// isCover returns true if the binary id is referenced by the coverpage
+func (p *parser) isCover(id string) bool {
+	return id != "" && slices.Contains(p.info.CoverIDs, id)
+}
+
+/*
+addCover adds the decoded cover to the book information keeping the order
+of coverpage, because binaries can be stored in any order
+*/
+func (p *parser) addCover(cover Cover) {
+	idx := slices.Index(p.info.CoverIDs, cover.ID)
+	pos := 0
+	for pos < len(p.info.Covers) && slices.Index(p.info.CoverIDs, p.info.Covers[pos].ID) < idx {
+		pos++
+	}
+	p.info.Covers = slices.Insert(p.info.Covers, pos, cover)
+	p.info.Cover = p.info.Covers[0]
+}
+
+/*
+skipForCover skips the elements that cannot contain the cover images: book
+bodies and binaries other than the covers. If the book does not refer to a
+cover the parsing is stopped at the first body. Returns true if the element
+has been skipped
+*/
+func (p *parser) skipForCover(se xml.StartElement) bool {
+	switch {
+	case se.Name.Local == "body" && len(p.info.CoverIDs) == 0:
+		p.finish(nil)
+		return true
+	case se.Name.Local == "body",
+		se.Name.Local == "binary" && !p.isCover(attrValue(se, "id")):
+		if err := p.decoder.Skip(); err != nil {
+			p.finish(p.classify(err))
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,124 @@
+package fb2text
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// coverBook has the front and the back cover, their binaries are stored in
+// the reverse order and separated by a binary that is not a cover
+const coverBook = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description><title-info><book-title>Cover</book-title>
+<coverpage><image l:href="#front.jpg"/><image l:href="#back.png"/></coverpage>
+</title-info></description>
+<body><section><p>Text</p><image l:href="#pic.gif"/></section></body>
+<binary id="back.png" content-type="image/png">BAU=</binary>
+<binary id="pic.gif" content-type="image/gif">Bw==</binary>
+<binary id="front.jpg" content-type="image/jpeg">AQID</binary>
+</FictionBook>`
+
+var (
+	frontCover = Cover{ID: "front.jpg", ContentType: "image/jpeg", Data: []byte{1, 2, 3}}
+	backCover  = Cover{ID: "back.png", ContentType: "image/png", Data: []byte{4, 5}}
+)
+
+func TestBookInfoCover(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		cover Cover
+	}{
+		{name: "metadata", cover: Cover{}},
+		{name: "body", opts: []Option{ParseBody()}, cover: frontCover},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, _, err := ParseBookFromReader(strings.NewReader(coverBook), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(info.Cover, tt.cover) {
+				t.Errorf("cover = %+v, want %+v", info.Cover, tt.cover)
+			}
+			if want := []string{"front.jpg", "back.png"}; !reflect.DeepEqual(info.CoverIDs, want) {
+				t.Errorf("cover ids = %q, want %q", info.CoverIDs, want)
+			}
+		})
+	}
+}
+
+func TestExtractCover(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "cover.fb2")
+	if err := os.WriteFile(fileName, []byte(coverBook), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fromReader := func(book string) func() (Cover, error) {
+		return func() (Cover, error) { return ExtractCoverFromReader(strings.NewReader(book)) }
+	}
+
+	tests := []struct {
+		name    string
+		extract func() (Cover, error)
+		cover   Cover
+		err     error
+	}{
+		{
+			name:    "file",
+			extract: func() (Cover, error) { return ExtractCover(fileName) },
+			cover:   frontCover,
+		},
+		{
+			name:    "reader",
+			extract: fromReader(coverBook),
+			cover:   frontCover,
+		},
+		{
+			name:    "no coverpage",
+			extract: fromReader(titleBook("T")),
+			err:     ErrNoCover,
+		},
+		{
+			name:    "no cover binary",
+			extract: fromReader(strings.NewReplacer(`id="front.jpg"`, `id="x"`, `id="back.png"`, `id="y"`).Replace(coverBook)),
+			err:     ErrNoCover,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cover, err := tt.extract()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(cover, tt.cover) {
+				t.Errorf("cover = %+v, want %+v", cover, tt.cover)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	want := []Cover{frontCover, backCover}
+
+	covers, err := ExtractCoversFromReader(strings.NewReader(coverBook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(covers, want) {
+		t.Errorf("ExtractCovers() = %+v, want %+v", covers, want)
+	}
+
+	info, _, err := ParseBookFromReader(strings.NewReader(coverBook), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info.Covers, want) {
+		t.Errorf("covers = %+v, want %+v", info.Covers, want)
+	}
+}
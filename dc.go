@@ -0,0 +1,186 @@
+package fb2text
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type dcMetadata struct {
+	XMLName      xml.Name `xml:"metadata"`
+	XmlnsDC      string   `xml:"xmlns:dc,attr"`
+	Title        string   `xml:"dc:title"`
+	Creators     []string `xml:"dc:creator"`
+	Contributors []string `xml:"dc:contributor"`
+	Subjects     []string `xml:"dc:subject"`
+	Description  string   `xml:"dc:description,omitempty"`
+	Publisher    string   `xml:"dc:publisher,omitempty"`
+	Date         string   `xml:"dc:date,omitempty"`
+	Type         string   `xml:"dc:type"`
+	Format       string   `xml:"dc:format"`
+	Identifiers  []string `xml:"dc:identifier"`
+	Sources      []string `xml:"dc:source"`
+	Language     string   `xml:"dc:language,omitempty"`
+	Relations    []string `xml:"dc:relation"`
+}
+
+/*
+WriteDublinCore writes the book information to w as Dublin Core XML
+metadata: title, authors as creators, translators as contributors, genres
+and keywords as subjects, annotation, publisher, date, ISBN and document ID
+as identifiers, source URLs, language, and series as relations
+*/
+func WriteDublinCore(w io.Writer, info BookInfo) error {
+	meta := dcMetadata{
+		XmlnsDC:     "http://purl.org/dc/elements/1.1/",
+		Title:       info.Title,
+		Description: info.Annotation,
+		Publisher:   info.PublishInfo.Publisher,
+		Date:        isoDate(info.Date),
+		Type:        "Text",
+		Format:      "application/x-fictionbook+xml",
+		Sources:     info.DocumentInfo.SrcURLs,
+		Language:    info.Language,
+	}
+	for _, a := range info.Authors {
+		meta.Creators = append(meta.Creators, a.DisplayName(FirstLast))
+	}
+	for _, a := range info.Translators {
+		meta.Contributors = append(meta.Contributors, a.DisplayName(FirstLast))
+	}
+	for _, g := range info.Genres {
+		meta.Subjects = append(meta.Subjects, g.DisplayName("en"))
+	}
+	meta.Subjects = append(meta.Subjects, info.KeywordTags()...)
+	if info.PublishInfo.ISBN != "" {
+		meta.Identifiers = append(meta.Identifiers, "urn:isbn:"+info.PublishInfo.ISBN)
+	}
+	if info.ID != "" {
+		meta.Identifiers = append(meta.Identifiers, info.ID)
+	}
+	for _, seq := range info.Sequences {
+		meta.Relations = append(meta.Relations, seq.Name)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(meta); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+type ldPerson struct {
+	Type           string `json:"@type"`
+	Name           string `json:"name"`
+	GivenName      string `json:"givenName,omitempty"`
+	AdditionalName string `json:"additionalName,omitempty"`
+	FamilyName     string `json:"familyName,omitempty"`
+	AlternateName  string `json:"alternateName,omitempty"`
+	Email          string `json:"email,omitempty"`
+	URL            string `json:"url,omitempty"`
+}
+
+type ldThing struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type ldBook struct {
+	Context           string     `json:"@context,omitempty"`
+	Type              string     `json:"@type"`
+	Name              string     `json:"name"`
+	Identifier        string     `json:"identifier,omitempty"`
+	Author            []ldPerson `json:"author,omitempty"`
+	Translator        []ldPerson `json:"translator,omitempty"`
+	InLanguage        string     `json:"inLanguage,omitempty"`
+	Genre             []string   `json:"genre,omitempty"`
+	Keywords          string     `json:"keywords,omitempty"`
+	Abstract          string     `json:"abstract,omitempty"`
+	DateCreated       string     `json:"dateCreated,omitempty"`
+	ISBN              string     `json:"isbn,omitempty"`
+	Publisher         *ldThing   `json:"publisher,omitempty"`
+	IsPartOf          *ldThing   `json:"isPartOf,omitempty"`
+	Position          int        `json:"position,omitempty"`
+	TranslationOfWork *ldBook    `json:"translationOfWork,omitempty"`
+}
+
+/*
+WriteJSONLD writes the book information to w as schema.org Book in JSON-LD
+format. It can be embedded into a web page or passed to a catalog that
+understands schema.org
+*/
+func WriteJSONLD(w io.Writer, info BookInfo) error {
+	book := ldBook{
+		Context:     "https://schema.org",
+		Type:        "Book",
+		Name:        info.Title,
+		Identifier:  info.ID,
+		Author:      ldPersons(info.Authors),
+		Translator:  ldPersons(info.Translators),
+		InLanguage:  info.Language,
+		Keywords:    strings.Join(info.KeywordTags(), ", "),
+		Abstract:    info.Annotation,
+		DateCreated: isoDate(info.Date),
+		ISBN:        info.PublishInfo.ISBN,
+	}
+	for _, g := range info.Genres {
+		book.Genre = append(book.Genre, g.DisplayName("en"))
+	}
+	if info.PublishInfo.Publisher != "" {
+		book.Publisher = &ldThing{Type: "Organization", Name: info.PublishInfo.Publisher}
+	}
+	for _, seq := range info.Sequences {
+		if seq.Parent == nil {
+			book.IsPartOf = &ldThing{Type: "BookSeries", Name: seq.Name}
+			book.Position = seq.Number
+			break
+		}
+	}
+	if src := info.Source; src.Title != "" {
+		book.TranslationOfWork = &ldBook{
+			Type:        "Book",
+			Name:        src.Title,
+			Author:      ldPersons(src.Authors),
+			InLanguage:  src.Language,
+			DateCreated: isoDate(src.Date),
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(book)
+}
+
+// ldPersons converts authors to schema.org persons
+func ldPersons(authors []Author) []ldPerson {
+	persons := make([]ldPerson, 0, len(authors))
+	for _, a := range authors {
+		person := ldPerson{
+			Type:           "Person",
+			Name:           a.DisplayName(FirstLast),
+			GivenName:      a.FirstName,
+			AdditionalName: a.MiddleName,
+			FamilyName:     a.LastName,
+		}
+		if person.Name != a.Nickname {
+			person.AlternateName = a.Nickname
+		}
+		if len(a.Emails) > 0 {
+			person.Email = a.Emails[0]
+		}
+		if len(a.HomePages) > 0 {
+			person.URL = a.HomePages[0]
+		}
+		persons = append(persons, person)
+	}
+
+	return persons
+}
@@ -0,0 +1,110 @@
+package fb2text
+
+import (
+	"bytes"
+	"testing"
+)
+
+// dcInfo is a book information with all fields written by the exporters
+var dcInfo = BookInfo{
+	ID:           "id-1",
+	Title:        "Andromeda",
+	Authors:      []Author{{FirstName: "Ivan", MiddleName: "A.", LastName: "Efremov", Emails: []string{"ie@x"}}},
+	Translators:  []Author{{Nickname: "tr"}},
+	Genres:       []Genre{{Code: "sf"}},
+	Keywords:     "space, future",
+	Language:     "ru",
+	Date:         Date{Text: "1957"},
+	Annotation:   "A novel",
+	Sequences:    []Sequence{{Name: "Great Ring", Number: 1}},
+	PublishInfo:  PublishInfo{Publisher: "Molodaya Gvardiya", ISBN: "978-5"},
+	DocumentInfo: DocumentInfo{SrcURLs: []string{"http://lib/1"}},
+	Source:       SourceInfo{Title: "Туманность Андромеды", Language: "ru"},
+}
+
+func TestWriteDublinCore(t *testing.T) {
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <dc:title>Andromeda</dc:title>
+  <dc:creator>Ivan A. Efremov</dc:creator>
+  <dc:contributor>tr</dc:contributor>
+  <dc:subject>Science Fiction</dc:subject>
+  <dc:subject>space</dc:subject>
+  <dc:subject>future</dc:subject>
+  <dc:description>A novel</dc:description>
+  <dc:publisher>Molodaya Gvardiya</dc:publisher>
+  <dc:date>1957</dc:date>
+  <dc:type>Text</dc:type>
+  <dc:format>application/x-fictionbook+xml</dc:format>
+  <dc:identifier>urn:isbn:978-5</dc:identifier>
+  <dc:identifier>id-1</dc:identifier>
+  <dc:source>http://lib/1</dc:source>
+  <dc:language>ru</dc:language>
+  <dc:relation>Great Ring</dc:relation>
+</metadata>
+`
+
+	var buf bytes.Buffer
+	if err := WriteDublinCore(&buf, dcInfo); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteDublinCore() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteJSONLD(t *testing.T) {
+	want := `{
+  "@context": "https://schema.org",
+  "@type": "Book",
+  "name": "Andromeda",
+  "identifier": "id-1",
+  "author": [
+    {
+      "@type": "Person",
+      "name": "Ivan A. Efremov",
+      "givenName": "Ivan",
+      "additionalName": "A.",
+      "familyName": "Efremov",
+      "email": "ie@x"
+    }
+  ],
+  "translator": [
+    {
+      "@type": "Person",
+      "name": "tr"
+    }
+  ],
+  "inLanguage": "ru",
+  "genre": [
+    "Science Fiction"
+  ],
+  "keywords": "space, future",
+  "abstract": "A novel",
+  "dateCreated": "1957",
+  "isbn": "978-5",
+  "publisher": {
+    "@type": "Organization",
+    "name": "Molodaya Gvardiya"
+  },
+  "isPartOf": {
+    "@type": "BookSeries",
+    "name": "Great Ring"
+  },
+  "position": 1,
+  "translationOfWork": {
+    "@type": "Book",
+    "name": "Туманность Андромеды",
+    "inLanguage": "ru"
+  }
+}
+`
+
+	var buf bytes.Buffer
+	if err := WriteJSONLD(&buf, dcInfo); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteJSONLD() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
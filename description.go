@@ -0,0 +1,99 @@
+package fb2text
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+/*
+ExtractDescription returns the raw XML of <description> element of the book
+fileName: title-info, document-info, publish-info, and custom-info exactly
+as they are written in the book. It allows to process the fields this
+package does not support, e.g. with XSLT. The book text is not read. The
+result is always in UTF-8, books in other encodings are converted
+*/
+func ExtractDescription(fileName string, opts ...Option) ([]byte, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return ExtractDescriptionFromReader(file, opts...)
+}
+
+/*
+ExtractDescriptionFromReader works the same way as ExtractDescription but
+reads the book from r. The stream can be a raw FB2, ZIP or GZIP archive
+*/
+func ExtractDescriptionFromReader(r io.Reader, opts ...Option) ([]byte, error) {
+	opt, err := newOption(opts)
+	if err != nil {
+		return nil, err
+	}
+	opt.parseBody = false
+
+	book, err := openBook(r, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer book.Close()
+
+	// the raw bytes read by the parser are kept to cut the description
+	// from them by the decoder offsets
+	var raw, converted bytes.Buffer
+	book.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(book.ReadCloser, &raw), book.ReadCloser}
+
+	p := newParser(book, opt)
+	// after the XML declaration the decoder reads the text converted to
+	// UTF-8, so the offsets point to the converted text from there
+	switchOffset := int64(-1)
+	p.decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		r, err := p.charsetReader(label, input)
+		if err != nil {
+			return nil, err
+		}
+		switchOffset = p.decoder.InputOffset()
+		return io.TeeReader(r, &converted), nil
+	}
+
+	start, end := int64(-1), int64(-1)
+	for end < 0 {
+		offset := p.decoder.InputOffset()
+		if !p.step() {
+			break
+		}
+		switch {
+		case start < 0 && len(p.tags) == 2 && p.tags[1] == "description":
+			start = offset
+		case start >= 0 && len(p.tags) < 2:
+			end = p.decoder.InputOffset()
+		}
+		p.lines = p.lines[:0]
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("%w: no description", ErrNotFB2)
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("%w: description is not closed", ErrMalformedXML)
+	}
+
+	data := raw.Bytes()
+	if switchOffset >= 0 {
+		data = append(data[:switchOffset:switchOffset], converted.Bytes()...)
+	}
+
+	return bytes.Clone(data[start:end]), nil
+}
@@ -0,0 +1,97 @@
+package fb2text
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// descriptionBook returns a book with the description in the given encoding
+func descriptionBook(encoding, description string) string {
+	return `<?xml version="1.0" encoding="` + encoding + `"?>
+<!-- <description>not this one</description> -->
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">` + description + `
+<body><section><p>Текст</p></section></body>
+</FictionBook>`
+}
+
+func TestExtractDescription(t *testing.T) {
+	const description = `<description><title-info><book-title>Тест</book-title>` +
+		`<annotation><p><![CDATA[</description>]]></p></annotation></title-info></description>`
+	encode := func(enc encoding.Encoding, s string) string {
+		data, err := enc.NewEncoder().String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name string
+		book string
+		want string
+		err  error
+	}{
+		{
+			name: "utf-8",
+			book: descriptionBook("utf-8", description),
+			want: description,
+		},
+		{
+			name: "windows-1251",
+			book: encode(charmap.Windows1251, descriptionBook("windows-1251", description)),
+			want: description,
+		},
+		{
+			name: "utf-16 with BOM",
+			book: encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), descriptionBook("UTF-16", description)),
+			want: description,
+		},
+		{
+			name: "utf-16 big endian without BOM",
+			book: encode(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), descriptionBook("UTF-16", description)),
+			want: description,
+		},
+		{
+			name: "no description",
+			book: descriptionBook("utf-8", ""),
+			err:  ErrNotFB2,
+		},
+		{
+			name: "not closed",
+			book: strings.Split(descriptionBook("utf-8", description), "</title-info>")[0],
+			err:  ErrMalformedXML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractDescriptionFromReader(strings.NewReader(tt.book))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("description = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUTF16Book(t *testing.T) {
+	book, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(descriptionBook("UTF-16", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, lines, err := ParseBookFromReader(strings.NewReader(book), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[1] != "Текст" {
+		t.Errorf("lines = %q, want the text of the book", lines)
+	}
+}
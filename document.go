@@ -0,0 +1,395 @@
+package fb2text
+
+import (
+	"io"
+	"os"
+)
+
+/*
+Document is a structured representation of FB2 book. Unlike the flat list of
+lines returned by ParseBook, it keeps the nesting of sections and groups
+paragraphs into titles, epigraphs, poems, and cites, so it can be used to
+build a table of contents or to render the book in a rich format
+*/
+type Document struct {
+	Info   BookInfo
+	Bodies []*Body
+}
+
+/*
+Body is a body of the book. The main text is in the first body with empty
+name, the following bodies usually contain notes or comments
+*/
+type Body struct {
+	Name string
+	Section
+}
+
+/*
+Section is a book section. Sections can be nested. ID is the id attribute of
+the section, Lang is the language of the section from xml:lang attribute of
+the section or its parents
+*/
+type Section struct {
+	ID         string
+	Lang       string
+	Title      *Title
+	Epigraphs  []*Epigraph
+	Annotation *Annotation
+	// Content is a list of section elements in document order. Elements after
+	// a subsection, which some books have though the schema does not allow
+	// them, are added to Content too, their position among Sections is lost
+	Content  []Node
+	Sections []*Section
+}
+
+// Node is an element of section content: *Paragraph, *EmptyLine, *Image, *Poem, *Cite, or *Table
+type Node interface {
+	isNode()
+}
+
+/*
+Paragraph is a line of text with emphasized parts described by Spans. Kind
+is the kind of the line, e.g. KindVerse, KindSubtitle, or KindCode, Lang is
+the language of the paragraph from xml:lang attribute
+*/
+type Paragraph struct {
+	Kind  Kind
+	Text  string
+	Spans []Span
+	Lang  string
+}
+
+// EmptyLine is a vertical space between paragraphs
+type EmptyLine struct{}
+
+/*
+Image is an image between paragraphs. ID is the id of the image binary, Alt
+is its alternative text, Caption is its title
+*/
+type Image struct {
+	ID      string
+	Alt     string
+	Caption string
+}
+
+// Title is a title of a body, a section, or a poem. It may contain several lines
+type Title struct {
+	Lines []*Paragraph
+}
+
+// Annotation is a summary of a section
+type Annotation struct {
+	Lines []*Paragraph
+}
+
+// Epigraph is a quotation with optional authors
+type Epigraph struct {
+	Lines   []*Paragraph
+	Authors []*Paragraph
+}
+
+/*
+Poem is a verse with optional title, epigraphs, and authors. Lines is a
+list of lines outside stanzas, e.g. the poem date
+*/
+type Poem struct {
+	Title     *Title
+	Epigraphs []*Epigraph
+	Stanzas   []*Stanza
+	Lines     []*Paragraph
+	Authors   []*Paragraph
+}
+
+// Stanza is a group of verses of a poem with optional title and subtitle
+type Stanza struct {
+	Title    *Title
+	Subtitle *Paragraph
+	Verses   []*Paragraph
+}
+
+// Cite is a block quotation with optional authors
+type Cite struct {
+	Epigraphs []*Epigraph
+	Lines     []*Paragraph
+	Authors   []*Paragraph
+}
+
+func (*Paragraph) isNode() {}
+func (*EmptyLine) isNode() {}
+func (*Image) isNode()     {}
+func (*Poem) isNode()      {}
+func (*Cite) isNode()      {}
+func (*Table) isNode()     {}
+
+/*
+ParseDocument parses the book fileName into a structured Document. The book
+body is always parsed, so option ParseBody is not required
+*/
+func ParseDocument(fileName string, opts ...Option) (*Document, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return ParseDocumentFromReader(file, opts...)
+}
+
+/*
+ParseDocumentFromReader works the same way as ParseDocument but reads the
+book from r. The stream can be a raw FB2, ZIP or GZIP archive. In case of
+error the function returns the document parsed so far with the error
+*/
+func ParseDocumentFromReader(r io.Reader, opts ...Option) (*Document, error) {
+	b := &docBuilder{doc: &Document{}}
+	info, err := VisitBookFromReader(r, b.visitor(), opts...)
+	b.doc.Info = info
+
+	return b.doc, err
+}
+
+/*
+docBuilder assembles Document from visitor events. stack contains the
+elements being built: *Section, *Title, *Epigraph, *Poem, *Stanza, *Cite,
+*Table, or *Annotation
+*/
+type docBuilder struct {
+	doc   *Document
+	stack []any
+	// lang is the language of the text being built
+	lang string
+	// kind is the kind of the line being built
+	kind Kind
+}
+
+func (b *docBuilder) visitor() Visitor {
+	return Visitor{
+		OnBodyStart:      b.bodyStart,
+		OnBodyEnd:        b.pop,
+		OnSectionStart:   b.sectionStart,
+		OnSectionID:      b.sectionID,
+		OnSectionEnd:     b.pop,
+		OnBlockStart:     b.blockStart,
+		OnBlockEnd:       func(string) { b.pop() },
+		OnTitle:          b.line,
+		OnEpigraph:       b.line,
+		OnEpigraphAuthor: b.epigraphAuthor,
+		OnCiteAuthor:     b.citeAuthor,
+		OnPoemAuthor:     b.poemAuthor,
+		OnStanzaSubtitle: b.stanzaSubtitle,
+		OnParagraph:      b.line,
+		OnEmptyLine:      b.emptyLine,
+		OnImage:          b.image,
+		OnImageCaption:   b.imageCaption,
+		OnTable:          b.table,
+		OnLanguage:       func(lang string) { b.lang = lang },
+		OnLine:           func(line Line) { b.kind = line.Kind },
+	}
+}
+
+func (b *docBuilder) top() any {
+	if len(b.stack) == 0 {
+		return nil
+	}
+
+	return b.stack[len(b.stack)-1]
+}
+
+func (b *docBuilder) pop() {
+	if len(b.stack) > 0 {
+		b.stack = b.stack[:len(b.stack)-1]
+	}
+}
+
+// section returns the innermost section being built
+func (b *docBuilder) section() *Section {
+	for i := len(b.stack) - 1; i >= 0; i-- {
+		if sec, ok := b.stack[i].(*Section); ok {
+			return sec
+		}
+	}
+
+	// text outside bodies, e.g. in a broken book
+	body := &Body{Section: Section{Lang: b.lang}}
+	b.doc.Bodies = append(b.doc.Bodies, body)
+	b.stack = append([]any{&body.Section}, b.stack...)
+
+	return &body.Section
+}
+
+func (b *docBuilder) bodyStart(name string) {
+	body := &Body{Name: name, Section: Section{Lang: b.lang}}
+	b.doc.Bodies = append(b.doc.Bodies, body)
+	b.stack = append(b.stack, &body.Section)
+}
+
+func (b *docBuilder) sectionStart() {
+	parent := b.section()
+	sec := &Section{Lang: b.lang}
+	parent.Sections = append(parent.Sections, sec)
+	b.stack = append(b.stack, sec)
+}
+
+func (b *docBuilder) sectionID(id string) {
+	if sec, ok := b.top().(*Section); ok {
+		sec.ID = id
+	}
+}
+
+func (b *docBuilder) blockStart(name string) {
+	var block any
+	switch name {
+	case "title":
+		title := &Title{}
+		switch parent := b.top().(type) {
+		case *Poem:
+			parent.Title = title
+		case *Stanza:
+			parent.Title = title
+		case *Section:
+			parent.Title = title
+		}
+		block = title
+	case "epigraph":
+		epi := &Epigraph{}
+		switch parent := b.top().(type) {
+		case *Poem:
+			parent.Epigraphs = append(parent.Epigraphs, epi)
+		case *Cite:
+			parent.Epigraphs = append(parent.Epigraphs, epi)
+		default:
+			sec := b.section()
+			sec.Epigraphs = append(sec.Epigraphs, epi)
+		}
+		block = epi
+	case "poem":
+		poem := &Poem{}
+		b.addNode(poem)
+		block = poem
+	case "stanza":
+		stanza := &Stanza{}
+		if poem, ok := b.top().(*Poem); ok {
+			poem.Stanzas = append(poem.Stanzas, stanza)
+		}
+		block = stanza
+	case "cite":
+		cite := &Cite{}
+		b.addNode(cite)
+		block = cite
+	case "annotation":
+		annotation := &Annotation{}
+		b.section().Annotation = annotation
+		block = annotation
+	case "table":
+		table := &Table{}
+		b.addNode(table)
+		block = table
+	}
+
+	b.stack = append(b.stack, block)
+}
+
+// addNode adds the node to the innermost section
+func (b *docBuilder) addNode(node Node) {
+	sec := b.section()
+	sec.Content = append(sec.Content, node)
+}
+
+// paragraph returns the paragraph of the line being built
+func (b *docBuilder) paragraph(text string, em []Span) *Paragraph {
+	return &Paragraph{Kind: b.kind, Text: text, Spans: em, Lang: b.lang}
+}
+
+func (b *docBuilder) line(text string, em []Span) {
+	par := b.paragraph(text, em)
+	switch parent := b.top().(type) {
+	case *Title:
+		parent.Lines = append(parent.Lines, par)
+	case *Epigraph:
+		parent.Lines = append(parent.Lines, par)
+	case *Poem:
+		parent.Lines = append(parent.Lines, par)
+	case *Stanza:
+		parent.Verses = append(parent.Verses, par)
+	case *Cite:
+		parent.Lines = append(parent.Lines, par)
+	case *Annotation:
+		parent.Lines = append(parent.Lines, par)
+	case *Table:
+		// the cells are added with the whole table
+	default:
+		b.addNode(par)
+	}
+}
+
+func (b *docBuilder) epigraphAuthor(text string, em []Span) {
+	if epi, ok := b.top().(*Epigraph); ok {
+		epi.Authors = append(epi.Authors, b.paragraph(text, em))
+		return
+	}
+
+	b.line(text, em)
+}
+
+func (b *docBuilder) citeAuthor(text string, em []Span) {
+	if cite, ok := b.top().(*Cite); ok {
+		cite.Authors = append(cite.Authors, b.paragraph(text, em))
+		return
+	}
+
+	b.line(text, em)
+}
+
+func (b *docBuilder) poemAuthor(text string, em []Span) {
+	if poem, ok := b.top().(*Poem); ok {
+		poem.Authors = append(poem.Authors, b.paragraph(text, em))
+		return
+	}
+
+	b.line(text, em)
+}
+
+func (b *docBuilder) stanzaSubtitle(text string, em []Span) {
+	if stanza, ok := b.top().(*Stanza); ok {
+		stanza.Subtitle = b.paragraph(text, em)
+		return
+	}
+
+	b.line(text, em)
+}
+
+func (b *docBuilder) emptyLine() {
+	switch parent := b.top().(type) {
+	case *Section:
+		parent.Content = append(parent.Content, &EmptyLine{})
+	case nil:
+		b.addNode(&EmptyLine{})
+	default:
+		b.kind = KindEmpty
+		b.line("", nil)
+	}
+}
+
+func (b *docBuilder) image(id, alt string) {
+	b.addNode(&Image{ID: id, Alt: alt})
+}
+
+func (b *docBuilder) imageCaption(text string) {
+	content := b.section().Content
+	if n := len(content); n > 0 {
+		if image, ok := content[n-1].(*Image); ok {
+			image.Caption = text
+		}
+	}
+}
+
+func (b *docBuilder) table(t Table) {
+	if table, ok := b.top().(*Table); ok {
+		*table = t
+	}
+}
@@ -0,0 +1,57 @@
+package fb2text
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocumentParagraphKinds(t *testing.T) {
+	const book = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><section><title><p>One</p></title>
+<p>Text</p>
+<subtitle>* * *</subtitle>
+<p><code>x := 1</code></p>
+<poem><stanza><v>Verse</v></stanza><date>1999</date></poem>
+<cite><p>Quote</p><empty-line/><text-author>Who</text-author></cite>
+</section></body>
+</FictionBook>`
+
+	doc, err := ParseDocumentFromReader(strings.NewReader(book))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec := doc.Bodies[0].Sections[0]
+	content := sec.Content
+	if len(content) != 5 {
+		t.Fatalf("got %d content nodes, want 5", len(content))
+	}
+	poem := content[3].(*Poem)
+	cite := content[4].(*Cite)
+
+	tests := []struct {
+		name string
+		par  *Paragraph
+		kind Kind
+		text string
+	}{
+		{"title", sec.Title.Lines[0], KindTitle, "One"},
+		{"paragraph", content[0].(*Paragraph), KindParagraph, "Text"},
+		{"subtitle", content[1].(*Paragraph), KindSubtitle, "* * *"},
+		{"code", content[2].(*Paragraph), KindCode, "x := 1"},
+		{"verse", poem.Stanzas[0].Verses[0], KindVerse, "Verse"},
+		{"poem date", poem.Lines[0], KindPoemDate, "1999"},
+		{"cite", cite.Lines[0], KindCite, "Quote"},
+		{"empty line", cite.Lines[1], KindEmpty, ""},
+		{"cite author", cite.Authors[0], KindCiteAuthor, "Who"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.par.Kind != tt.kind || tt.par.Text != tt.text {
+				t.Errorf("paragraph = %v %q, want %v %q", tt.par.Kind, tt.par.Text, tt.kind, tt.text)
+			}
+		})
+	}
+}
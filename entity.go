@@ -0,0 +1,57 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// entityDecl matches an entity declaration of the internal DTD subset
+var entityDecl = regexp.MustCompile(`<!ENTITY\s+([^\s%"']+)\s+(?:"([^"]*)"|'([^']*)')\s*>`)
+
+// charRef matches a character reference, e.g. &#160; or &#xA0;
+var charRef = regexp.MustCompile(`&#(x[0-9a-fA-F]+|[0-9]+);`)
+
+/*
+directive adds the entities declared in the internal DTD subset of the book,
+e.g. <!DOCTYPE FictionBook [<!ENTITY nbsp "&#160;">]>, to the entities of
+the decoder. HTML entities are known without declaration
+*/
+func (p *parser) directive(d xml.Directive) {
+	if !strings.HasPrefix(string(d), "DOCTYPE") {
+		return
+	}
+
+	decls := entityDecl.FindAllStringSubmatch(string(d), -1)
+	if len(decls) == 0 {
+		return
+	}
+	// the decoder shares the HTML entities with other parsers until the book
+	// declares its own
+	entities := maps.Clone(p.decoder.Entity)
+	for _, decl := range decls {
+		value := decl[2]
+		if value == "" {
+			value = decl[3]
+		}
+		entities[decl[1]] = charRef.ReplaceAllStringFunc(value, resolveCharRef)
+	}
+	p.decoder.Entity = entities
+}
+
+// resolveCharRef returns the character of the character reference
+func resolveCharRef(ref string) string {
+	num := strings.TrimSuffix(strings.TrimPrefix(ref, "&#"), ";")
+	base := 10
+	if hex, ok := strings.CutPrefix(num, "x"); ok {
+		num, base = hex, 16
+	}
+	code, err := strconv.ParseInt(num, base, 32)
+	if err != nil {
+		return ref
+	}
+
+	return string(rune(code))
+}
@@ -0,0 +1,23 @@
+package fb2text
+
+import "errors"
+
+// Errors returned by the parsing functions. Use errors.Is to check them
+var (
+	// ErrNotFB2 means the file is not FB2: it is not XML or its root
+	// element is not FictionBook
+	ErrNotFB2 = errors.New("fb2text: not an FB2 file")
+	// ErrNoFB2InZip means ZIP archive does not contain the requested FB2 file
+	ErrNoFB2InZip = errors.New("fb2text: no FB2 file in the archive")
+	// ErrUnsupportedEncoding means the book text encoding is unknown
+	ErrUnsupportedEncoding = errors.New("fb2text: unsupported encoding")
+	// ErrMalformedXML means the book is not a well-formed XML
+	ErrMalformedXML = errors.New("fb2text: malformed XML")
+	// ErrInvalidOption means parsing options are invalid or conflict
+	ErrInvalidOption = errors.New("fb2text: invalid option")
+	// ErrNoCover means the book does not have a cover image
+	ErrNoCover = errors.New("fb2text: no cover image")
+	// ErrNoSection means the book does not have the requested section, see
+	// ParseSection
+	ErrNoSection = errors.New("fb2text: no such section")
+)
@@ -0,0 +1,61 @@
+package fb2text
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	parse := func(book string, opts ...Option) func() error {
+		return func() error {
+			_, _, err := ParseBookFromReader(strings.NewReader(book), opts...)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name  string
+		parse func() error
+		err   error
+	}{
+		{
+			name:  "not XML",
+			parse: parse("plain text"),
+			err:   ErrNotFB2,
+		},
+		{
+			name:  "wrong root",
+			parse: parse(`<?xml version="1.0"?><html><body/></html>`),
+			err:   ErrNotFB2,
+		},
+		{
+			name:  "malformed XML",
+			parse: parse(strings.Replace(titleBook("T"), "</p>", "</emphasis>", 1), ParseBody()),
+			err:   ErrMalformedXML,
+		},
+		{
+			name:  "unsupported encoding",
+			parse: parse(strings.Replace(titleBook("T"), "utf-8", "x-unknown", 1)),
+			err:   ErrUnsupportedEncoding,
+		},
+		{
+			name:  "conflicting options",
+			parse: parse(titleBook("T"), WithZipEntry("a.fb2"), WithZipEntryIndex(0)),
+			err:   ErrInvalidOption,
+		},
+		{
+			name:  "no FB2 in zip",
+			parse: func() error { _, _, err := ParseBookBytes(zipBooks(t), WithZipEntry("c.fb2")); return err },
+			err:   ErrNoFB2InZip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.parse(); !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,34 +1,15 @@
 package fb2text
 
 import (
-	"archive/zip"
+	"bytes"
 	"encoding/xml"
+	"io"
+	"io/fs"
 	"net/http"
 	"os"
-	"strings"
-
-	xs "github.com/huandu/xstrings"
-	"golang.org/x/net/html/charset"
+	"slices"
 )
 
-/*
-BookInfo is a short information about FB2 book. It supports few tags
-only: book title, first and last author names, sequence, genre, and
-text language (not the original book language)
-*/
-type BookInfo struct {
-	Authors  []Author
-	Title    string
-	Sequence string
-	Language string
-	Genre    string
-}
-
-type Author struct {
-	FirstName string
-	LastName  string
-}
-
 /*
 IsZipFile checks if the file is ZIP archive.
 Returns true is the file is ZIP or GZIP archive and false otherwise
@@ -54,13 +35,18 @@ func IsZipFile(filePath string) bool {
 }
 
 func isInBookInfo(path []string) bool {
+	return isInDescription(path, "title-info")
+}
+
+// isInDescription returns true if the path is inside the part of <description>
+func isInDescription(path []string, part string) bool {
 	if len(path) < 3 {
 		return false
 	}
 
 	return path[0] == "FictionBook" &&
 		path[1] == "description" &&
-		path[2] == "title-info"
+		path[2] == part
 }
 
 func isInBookContent(path []string) bool {
@@ -72,6 +58,129 @@ func isInBookContent(path []string) bool {
 		path[1] == "body"
 }
 
+/*
+isInEpigraph returns true if the path is inside an epigraph of the book
+content, including the poems and cites of the epigraph
+*/
+func isInEpigraph(path []string) bool {
+	return isInBookContent(path) && slices.Contains(path, "epigraph")
+}
+
+// isInline returns true if the element is a part of a paragraph text
+func isInline(name string) bool {
+	switch name {
+	case "a", "sup", "sub", "code", "strikethrough", "style":
+		return true
+	}
+
+	return false
+}
+
+// inlineStyles are the span styles of inline elements without attributes
+var inlineStyles = map[string]SpanStyle{
+	"code": StyleCode,
+	"sub":  StyleSub,
+	"sup":  StyleSup,
+}
+
+// isText returns true if the element contains the text of a line
+func isText(name string) bool {
+	switch name {
+	case "p", "v", "subtitle", "text-author", "td", "th", "emphasis", "strong":
+		return true
+	}
+
+	return isInline(name)
+}
+
+/*
+attrValue returns the value of the attribute name of the element or empty
+string. The attribute without namespace wins, e.g. type="note" of a link is
+returned instead of xlink:type="simple"
+*/
+func attrValue(se xml.StartElement, name string) string {
+	value, found := "", false
+	for _, attr := range se.Attr {
+		if attr.Name.Local != name {
+			continue
+		}
+		if attr.Name.Space == "" {
+			return attr.Value
+		}
+		if !found {
+			value, found = attr.Value, true
+		}
+	}
+
+	return value
+}
+
+// xlinkNamespace is the namespace of href attributes of links and images
+const xlinkNamespace = "http://www.w3.org/1999/xlink"
+
+/*
+hrefValue returns the href attribute of a link or an image. The attribute in
+xlink namespace wins whatever its prefix is, then the attribute with the
+usual prefixes(xlink, l) when the namespace is not declared or without a
+prefix, then href with any other prefix
+*/
+func hrefValue(se xml.StartElement) string {
+	value, rank := "", 0
+	for _, attr := range se.Attr {
+		if attr.Name.Local != "href" {
+			continue
+		}
+		switch attr.Name.Space {
+		case xlinkNamespace:
+			return attr.Value
+		case "xlink", "l", "":
+			if rank < 2 {
+				value, rank = attr.Value, 2
+			}
+		default:
+			if rank < 1 {
+				value, rank = attr.Value, 1
+			}
+		}
+	}
+
+	return value
+}
+
+// xmlLang returns the value of xml:lang attribute of the element or empty string
+func xmlLang(se xml.StartElement) string {
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "lang" &&
+			(attr.Name.Space == "http://www.w3.org/XML/1998/namespace" || attr.Name.Space == "xml") {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// isBlock returns true if the element is a container of paragraphs
+func isBlock(name string) bool {
+	switch name {
+	case "title", "epigraph", "poem", "stanza", "cite", "table", "annotation":
+		return true
+	default:
+		return false
+	}
+}
+
+// isPoemTitle returns true if the path is inside the title of a poem
+func isPoemTitle(path []string) bool {
+	n := len(path)
+	return n >= 2 && path[n-1] == "title" && path[n-2] == "poem"
+}
+
+// isStanzaTitle returns true if the path is inside the title of a stanza
+func isStanzaTitle(path []string) bool {
+	n := len(path)
+	return n >= 2 && path[n-1] == "title" && path[n-2] == "stanza"
+}
+
 func isInside(path []string, sectionName string) bool {
 	n := len(path) - 1
 	if n < 0 {
@@ -101,24 +210,40 @@ fileName - path to file contains FB2 formatted text. It can be ZIP archive,
 
 	the function automatically unpack zip files
 
-parseBody - if parseBody is false the function stops right after it hits the
+opts - parsing options, see Option. Without options the function stops right
 
-	first 'body' tag. By this time all book information is read. The parameter
-	can be used for quick read of book properties without parsing the entire
-	file
+	after it hits the first 'body' tag. By this time all book information is
+	read, so it can be used for quick read of book properties without parsing
+	the entire file. Option ParseBody makes the function parse the book text,
+	other options(MaxLines, ExpandNotes, WithZipEntry, etc.) change what is
+	parsed and how
 
-Returns information about book[see BookInfo structure] and (if parseBody equals
+Returns information about book[see BookInfo structure], (if option ParseBody
 
-	true) the parsed FB2 text in internal format. Please read more about format
-	below.
+	is set) the parsed FB2 text in internal format, and an error if the file
+	cannot be read or is not a well-formed FB2. The errors can be checked with
+	errors.Is: ErrNotFB2, ErrMalformedXML, ErrInvalidOption for conflicting or
+	invalid options, etc. In case of error the function returns everything
+	parsed before the error occurred. Please read more about format below.
 
 All tags are enclosed in double curly brackets, like "{{section}}"
 Since terminal is not rich with GUI features, only few FB2 tags are added
 to output text. Existing internal tags:
 The following tags are always at the very beginning of the line:
-{{section}} - defines section start. Default format adds extra empty line
+{{section}} - defines section start. Default format adds extra empty line.
+
+	Nested sections have their depth in the marker: {{section:2}} for a
+	subsection, {{section:3}} for its subsection, etc. The id of a section is
+	added after '#': {{section#ch1}} or {{section:2#ch1.1}}
+
 {{title}} - defines title line. There can be several title lines in a row.
 
+	The title of a top-level section has {{title}} marker, the titles of
+	bodies and nested sections have their depth in the marker: {{title:0}}
+	for a body title, {{title:2}} for a subsection title, etc. So the title
+	page of a book or its part({{title:0}}) can be styled separately from the
+	chapter headers, e.g. with a page break after it
+
 	Default format justify the title in the center of screen if title length is
 	smaller than screen width. Otherwise it is displayed as regular paragraph
 
@@ -126,17 +251,113 @@ The following tags are always at the very beginning of the line:
 
 	lines, calculates the maximal width and then format all epigraph lines to make
 	them right justified in such way that the longest string ends at the right
-	edge of the screen
+	edge of the screen. Epigraphs of a body, a section, a poem, or a cite are
+	marked the same way, the paragraphs of cites inside an epigraph too
 
 {{epiauth}} - defines author of the epigraph text start. Default format treats
 
 	this tag as if it is {{epi}} one.
 
+{{poem}} - defines poem start. The line does not have any text, the poem title
+
+	and verses follow it
+
+{{stanza}} - defines stanza start. The line does not have any text
+
+{{verse}} - defines a line of a poem. Default format keeps every verse on a
+
+	separate line
+
+{{subtitle}} - defines a subtitle inside a section, e.g. "* * *" scene break.
+
+	Default format justify it in the center of screen
+
+{{code}} - defines a paragraph of code. The whitespace of the code is kept,
+
+	the paragraph can contain line breaks. Default format does not wrap it
+
+{{annotation}} - defines a line of a section annotation(e.g. a chapter summary).
+
+	It follows the section title
+
+{{cite}} - defines a line of a block quotation. Default format indents it
+
+{{citeauth}} - defines author of a block quotation. Default format aligns it
+
+	to the right edge of the screen
+
+{{poemauth}} - defines author of a poem. Default format aligns it to the right
+
+	edge of the screen, the same as {{citeauth}}
+
+{{poemtitle}} - defines a line of a poem title. Default format justify it in
+
+	the center of screen
+
+{{poemsubtitle}} - defines a subtitle of a poem between its stanzas. Default
+
+	format justify it in the center of screen
+
+{{stanzatitle}} - defines a line of a stanza title, e.g. a song verse header.
+
+	Default format justify it in the center of screen
+
+{{stanzasubtitle}} - defines a subtitle of a stanza. Default format justify it
+
+	in the center of screen
+
+{{poemdate}} - defines the date of a poem. Default format aligns it to the
+
+	right edge of the screen
+
+{{caption}} - defines a caption of the image on the previous line. It is the
+
+	title of the image. FB2 has no caption element, so the paragraphs after
+	an image are regular paragraphs
+
+{{date}} - defines a date outside poems, e.g. the date of a letter or a diary
+
+	entry. Default format aligns it to the right edge of the screen
+
+{{note}} - defines a line of a footnote inserted after the paragraph that
+
+	references it when option ExpandNotes is set, or a line of the notes
+	section at the end of the text when option EndNotes is set. The first line of a note
+	starts with the note title in brackets, e.g. "[1] "
+
 The following tags can be in any place of the string, that is why thay have
 starting and ending markers:
 {{emon}} and {{emoff}} - defines emphasized text started. Default format skips
-these tags and does nothing. In original FB2 two tags are mapped to {{emon}}:
-<strong> and <emphasis>
+these tags and does nothing. In original FB2 <emphasis> is mapped to {{emon}}
+{{strongon}} and {{strongoff}} - defines strong text. In original FB2
+<strong> is mapped to {{strongon}}, with option CombineStrong it is mapped
+to {{emon}}
+{{note:ID}} and {{noteoff}} - defines footnote reference, e.g. "[1]". ID is the
+id of the note section in the notes body, see BookInfo.Notes
+{{code}} and {{codeoff}} - defines code inside a paragraph, its whitespace is
+kept
+{{sub}} and {{suboff}}, {{sup}} and {{supoff}} - defines subscript and
+superscript text, e.g. H{{sub}}2{{suboff}}O
+{{lb}} - defines "{" of the text that is escaped not to be taken for the start
+of a marker, e.g. "{{" of the text is "{{lb}}{". Use Unescape to restore it
+{{rb}}, {{bar}}, and {{hash}} - define "}", "|", and "#" of a marker argument:
+an id, a target, or the alternative text of an image, "{" of the argument is
+{{lb}}, e.g. {{link:http://x/{{rb}}}}. The escape markers are the only
+markers inside a marker, so a marker ends at the first "}}" that does not
+end an escape marker. Use Unescape to restore the argument
+{{unknown:NAME}} and {{unknownoff}} - defines text of an unknown element NAME
+with option WithUnknownElements(UnknownMarker)
+{{style:NAME}} and {{styleoff}} - defines text with a named style of the book,
+e.g. a letter or handwriting. NAME is the value of the name attribute
+{{link:URL}} and {{linkoff}} - defines a link to an external resource
+{{anchor:ID}} and {{anchoroff}} - defines a link to the element of the book
+with the given id
+{{image:ID|ALT}} - defines an image, ID is the id of the image binary, ALT is
+the alternative text of the image. An image outside paragraphs is a separate
+line
+
+The same lines are available as typed values without markers, see
+ParseBookLines and Line.
 
 If a parsed string does not start with "{{" it means the string is regular
 paragraph of text. Default format separates the section to lines not longer
@@ -145,155 +366,114 @@ just divided at screen width position. If option 'justify' is set then all
 string of the paragraph(except the last one) are expanded with extra spaces to
 make all string the same widthop
 */
-func ParseBook(fileName string, opts ...FOption) (BookInfo, []string) {
-	opt := option{}
+func ParseBook(fileName string, opts ...Option) (BookInfo, []string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return BookInfo{}, make([]string, 0), err
+	}
+	defer file.Close()
 
-	for _, fun := range opts {
-		opt = fun(opt)
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
 	}
 
-	isZip := IsZipFile(fileName)
+	return ParseBookFromReader(file, opts...)
+}
 
-	lines := make([]string, 0)
-	var binfo BookInfo
-	tags := make([]string, 0, 10)
+/*
+ParseMetadata reads only information about the book from the file fileName.
+It is the fast path for library scanners: the parsing stops right at the
+first 'body' tag, so the book text is never read. Option ParseBody is
+ignored, all other options of ParseBook are supported
+*/
+func ParseMetadata(fileName string, opts ...Option) (BookInfo, error) {
+	opts = append(opts, func(o option) option {
+		o.parseBody = false
+		return o
+	})
+	info, _, err := ParseBook(fileName, opts...)
+
+	return info, err
+}
 
-	var decoder *xml.Decoder
+/*
+ParseBookFromReader works the same way as ParseBook but reads FB2 from r
+instead of a file. The stream can be a raw FB2, ZIP or GZIP archive, the
+format is detected automatically by the first bytes of the stream.
+
+ZIP archive cannot be read sequentially, so if r does not implement
+io.ReaderAt or the stream size is unknown(see option SizeHint) the whole
+archive is read into memory before parsing. Raw FB2 and GZIP streams are
+parsed on the fly
+*/
+func ParseBookFromReader(r io.Reader, opts ...Option) (BookInfo, []string, error) {
+	info, lines, err := ParseBookLinesFromReader(r, opts...)
+	return info, lines.Strings(), err
+}
 
-	if isZip {
-		zp, err := zip.OpenReader(fileName)
-		if err != nil {
-			return binfo, lines
-		}
+/*
+ParseBookLines works the same way as ParseBook but returns typed lines
+instead of strings in the internal format. Line kind and emphasized parts of
+the text are kept in separate fields, so there is no need to parse "{{...}}"
+markers. Use Lines.Strings to convert the result to the internal format
+*/
+func ParseBookLines(fileName string, opts ...Option) (BookInfo, Lines, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return BookInfo{}, make(Lines, 0), err
+	}
+	defer file.Close()
 
-		defer zp.Close()
-
-		for _, f := range zp.File {
-			if strings.HasSuffix(f.Name, ".fb2") {
-				zipFb2, err := f.Open()
-				if err != nil {
-					return binfo, lines
-				}
-				decoder = xml.NewDecoder(zipFb2)
-				defer zipFb2.Close()
-				break
-			}
-		}
-	} else {
-		xmlFile, err := os.Open(fileName)
-		if err != nil {
-			return binfo, lines
-		}
-		defer xmlFile.Close()
-		decoder = xml.NewDecoder(xmlFile)
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
 	}
 
-	if decoder == nil {
-		return binfo, lines
+	return ParseBookLinesFromReader(file, opts...)
+}
+
+/*
+ParseBookLinesFromReader works the same way as ParseBookFromReader but
+returns typed lines instead of strings in the internal format
+*/
+func ParseBookLinesFromReader(r io.Reader, opts ...Option) (BookInfo, Lines, error) {
+	lines := make(Lines, 0)
+	sc, err := NewScanner(r, opts...)
+	if err != nil {
+		return BookInfo{}, lines, err
 	}
+	defer sc.Close()
 
-	decoder.CharsetReader = charset.NewReaderLabel
+	for sc.Scan() {
+		lines = append(lines, sc.Line())
+	}
 
-	var currLine string
-	for {
-		t, _ := decoder.Token()
-		if t == nil {
-			break
-		}
+	return sc.Info(), lines, sc.Err()
+}
 
-		// Inspect the type of the token just read.
-		switch se := t.(type) {
-		case xml.StartElement:
-			if !opt.parseBody && se.Name.Local == "body" {
-				return binfo, lines
-			}
+/*
+ParseBookBytes works the same way as ParseBook but parses the book content
+stored in memory. data can be a raw FB2, ZIP or GZIP archive
+*/
+func ParseBookBytes(data []byte, opts ...Option) (BookInfo, []string, error) {
+	opts = append([]Option{SizeHint(int64(len(data)))}, opts...)
+	return ParseBookFromReader(bytes.NewReader(data), opts...)
+}
 
-			if se.Name.Local == "empty-line" && !opt.skipSystemLines {
-				lines = append(lines, "")
-				currLine = ""
-			} else if se.Name.Local == "section" && !opt.skipSystemLines {
-				lines = append(lines, "{{section}}")
-				currLine = ""
-			} else if (se.Name.Local == "emphasis" || se.Name.Local == "strong") && !opt.skipSystemLines {
-				currLine += "{{emon}}"
-			} else if se.Name.Local == "sequence" {
-				for i := 0; i < len(se.Attr); i++ {
-					if se.Attr[i].Name.Local == "name" {
-						binfo.Sequence = se.Attr[i].Value
-					}
-				}
-			} else {
-				if se.Name.Local == "text-author" && isInside(tags, "epigraph") {
-					currLine = "{{epiauth}}"
-				} else if se.Name.Local == "p" {
-					if isInside(tags, "epigraph") {
-						currLine = "{{epi}}"
-					} else if isInside(tags, "title") {
-						currLine = "{{title}}"
-					} else {
-						currLine = ""
-					}
-				} else {
-					currLine = ""
-				}
-			}
-			tags = append(tags, se.Name.Local)
-		case xml.EndElement:
-			if tags[len(tags)-1] != se.Name.Local {
-				panic("Invalid fb2")
-			}
-			tags = tags[:len(tags)-1]
-			if isInBookInfo(tags) {
-				if se.Name.Local == "genre" {
-					binfo.Genre = currLine
-				} else if se.Name.Local == "first-name" && isInside(tags, "author") {
-					if len(binfo.Authors) > 0 &&
-						binfo.Authors[len(binfo.Authors)-1].FirstName == "" {
-						last := len(binfo.Authors) - 1
-						author := binfo.Authors[last]
-						author.FirstName = currLine
-						binfo.Authors[last] = author
-					} else {
-						binfo.Authors = append(binfo.Authors, Author{FirstName: currLine})
-					}
-				} else if se.Name.Local == "last-name" && isInside(tags, "author") {
-					if len(binfo.Authors) > 0 &&
-						binfo.Authors[len(binfo.Authors)-1].LastName == "" {
-						last := len(binfo.Authors) - 1
-						author := binfo.Authors[last]
-						author.LastName = currLine
-						binfo.Authors[last] = author
-					} else {
-						binfo.Authors = append(binfo.Authors, Author{LastName: currLine})
-					}
-				} else if se.Name.Local == "book-title" {
-					binfo.Title = currLine
-				} else if se.Name.Local == "lang" {
-					binfo.Language = currLine
-				}
-			} else if isInBookContent(tags) {
-				if se.Name.Local == "body" {
-					return binfo, lines
-				} else if se.Name.Local == "emphasis" || se.Name.Local == "strong" {
-					currLine += "{{emoff}}"
-				} else {
-					if currLine != "" {
-						lines = append(lines, currLine)
-					}
-					currLine = ""
-				}
-			} else {
-				currLine = ""
-			}
-		case xml.CharData:
-			ss := string(se)
-			newLines := xs.Count(ss, "\n\r ")
-			if newLines != len(ss) {
-				ss = xs.Squeeze(xs.Translate(ss, "\n\r", "  "), " ")
-				currLine += ss
-			}
-		}
+/*
+ParseBookFS works the same way as ParseBook but reads the file from the file
+system fsys. It allows to parse books from embed.FS, archives opened as fs.FS,
+and any other file system abstraction
+*/
+func ParseBookFS(fsys fs.FS, path string, opts ...Option) (BookInfo, []string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return BookInfo{}, make([]string, 0), err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
 	}
 
-	return binfo, lines
+	return ParseBookFromReader(file, opts...)
 }
@@ -0,0 +1,267 @@
+package fb2text
+
+import "strings"
+
+/*
+DisplayName returns the human readable name of the genre in the language
+lang, e.g. "Heroic Fantasy" for sf_heroic. English and Russian names are
+available, lang is a language code like "en", "ru", or "ru-RU". For other
+languages the English name is returned. Unknown genre codes are returned as
+is
+*/
+func (g Genre) DisplayName(lang string) string {
+	name, ok := genreNames[g.Code]
+	if !ok {
+		return g.Code
+	}
+
+	lang = strings.ToLower(lang)
+	if lang == "ru" || strings.HasPrefix(lang, "ru-") || strings.HasPrefix(lang, "ru_") {
+		return name.ru
+	}
+
+	return name.en
+}
+
+// genreName is the name of a genre in the supported languages
+type genreName struct {
+	en, ru string
+}
+
+// genreNames contains the genres of FB2 2.1 genre list and common genres
+// added by online libraries
+var genreNames = map[string]genreName{
+	// science fiction and fantasy
+	"sf_history":         {"Alternative History", "Альтернативная история"},
+	"sf_action":          {"Action Science Fiction", "Боевая фантастика"},
+	"sf_epic":            {"Epic Science Fiction", "Эпическая фантастика"},
+	"sf_heroic":          {"Heroic Fantasy", "Героическая фантастика"},
+	"sf_detective":       {"Detective Science Fiction", "Детективная фантастика"},
+	"sf_cyberpunk":       {"Cyberpunk", "Киберпанк"},
+	"sf_space":           {"Space Science Fiction", "Космическая фантастика"},
+	"sf_social":          {"Social Science Fiction", "Социально-психологическая фантастика"},
+	"sf_horror":          {"Horror and Mystic", "Ужасы и мистика"},
+	"sf_humor":           {"Humorous Science Fiction", "Юмористическая фантастика"},
+	"sf_fantasy":         {"Fantasy", "Фэнтези"},
+	"sf":                 {"Science Fiction", "Научная фантастика"},
+	"sf_postapocalyptic": {"Post-apocalyptic", "Постапокалипсис"},
+	"sf_etc":             {"Other Science Fiction", "Фантастика: прочее"},
+	"sf_stimpank":        {"Steampunk", "Стимпанк"},
+	"sf_fantasy_city":    {"Urban Fantasy", "Городское фэнтези"},
+	"sf_mystic":          {"Mystic", "Мистика"},
+	"sf_space_opera":     {"Space Opera", "Космическая опера"},
+	"popadanec":          {"Time-travel Fiction", "Попаданцы"},
+	"hronoopera":         {"Chrono Opera", "Хроноопера"},
+	"fairy_fantasy":      {"Fairy Tale Fantasy", "Сказочная фантастика"},
+
+	// detectives and thrillers
+	"det_classic":   {"Classical Detective", "Классический детектив"},
+	"det_police":    {"Police Stories", "Полицейский детектив"},
+	"det_action":    {"Action", "Боевик"},
+	"det_irony":     {"Ironical Detective", "Иронический детектив"},
+	"det_history":   {"Historical Detective", "Исторический детектив"},
+	"det_espionage": {"Espionage Detective", "Шпионский детектив"},
+	"det_crime":     {"Crime Detective", "Криминальный детектив"},
+	"det_political": {"Political Detective", "Политический детектив"},
+	"det_maniac":    {"Maniacs", "Маньяки"},
+	"det_hard":      {"Hard-boiled", "Крутой детектив"},
+	"thriller":      {"Thriller", "Триллер"},
+	"detective":     {"Detective", "Детектив"},
+	"det_cozy":      {"Cozy Mystery", "Уютный детектив"},
+
+	// prose
+	"prose_classic":      {"Classics Prose", "Классическая проза"},
+	"prose_history":      {"Historical Prose", "Историческая проза"},
+	"prose_contemporary": {"Contemporary Prose", "Современная проза"},
+	"prose_counter":      {"Counterculture", "Контркультура"},
+	"prose_rus_classic":  {"Russian Classics", "Русская классическая проза"},
+	"prose_su_classics":  {"Soviet Classics", "Советская классическая проза"},
+	"prose_military":     {"Military Prose", "Военная проза"},
+	"prose":              {"Prose", "Проза"},
+	"prose_abs":          {"Absurdist Prose", "Фантасмагория, абсурдистская проза"},
+	"prose_neformatny":   {"Experimental Prose", "Экспериментальная, неформатная проза"},
+	"prose_magic":        {"Magical Realism", "Магический реализм"},
+	"aphorisms":          {"Aphorisms", "Афоризмы"},
+	"epistolary_fiction": {"Epistolary Fiction", "Эпистолярная проза"},
+	"story":              {"Short Story", "Рассказ"},
+	"gothic_novel":       {"Gothic Novel", "Готический роман"},
+	"essay":              {"Essay", "Эссе, очерк, этюд, набросок"},
+	"great_story":        {"Novella", "Повесть"},
+	"roman":              {"Novel", "Роман"},
+	"short_story":        {"Short Story", "Короткий рассказ"},
+	"prose_sentimental":  {"Sentimental Prose", "Сентиментальная проза"},
+
+	// love stories
+	"love_contemporary": {"Contemporary Romance", "Современные любовные романы"},
+	"love_history":      {"Historical Romance", "Исторические любовные романы"},
+	"love_detective":    {"Detective Romance", "Остросюжетные любовные романы"},
+	"love_short":        {"Short Romance", "Короткие любовные романы"},
+	"love_erotica":      {"Erotica", "Эротика"},
+	"love":              {"Romance", "Любовные романы"},
+	"love_sf":           {"Romantic Fantasy", "Любовное фэнтези, любовно-фантастические романы"},
+
+	// adventures
+	"adv_western":  {"Western", "Вестерн"},
+	"adv_history":  {"History Adventure", "Исторические приключения"},
+	"adv_indian":   {"Indians", "Приключения про индейцев"},
+	"adv_maritime": {"Maritime Fiction", "Морские приключения"},
+	"adv_geo":      {"Travel and Geography", "Путешествия и география"},
+	"adv_animal":   {"Nature and Animals", "Природа и животные"},
+	"adventure":    {"Adventure", "Приключения"},
+
+	// children
+	"child_tale":      {"Fairy Tales", "Сказка"},
+	"child_verse":     {"Children's Verses", "Детские стихи"},
+	"child_prose":     {"Children's Prose", "Детская проза"},
+	"child_sf":        {"Children's Science Fiction", "Детская фантастика"},
+	"child_det":       {"Children's Action", "Детские остросюжетные"},
+	"child_adv":       {"Children's Adventure", "Детские приключения"},
+	"child_education": {"Children's Education", "Детская образовательная литература"},
+	"children":        {"Children's Literature", "Детская литература"},
+	"child_folklore":  {"Children's Folklore", "Детский фольклор"},
+
+	// poetry and dramaturgy
+	"poetry":           {"Poetry", "Поэзия"},
+	"dramaturgy":       {"Dramaturgy", "Драматургия"},
+	"poetry_classical": {"Classical Poetry", "Классическая поэзия"},
+	"poetry_modern":    {"Modern Poetry", "Современная поэзия"},
+	"lyrics":           {"Lyrics", "Лирика"},
+	"palindromes":      {"Palindromes", "Визуальная и экспериментальная поэзия, верлибры, палиндромы"},
+	"song_poetry":      {"Song Poetry", "Песенная поэзия"},
+	"tragedy":          {"Tragedy", "Трагедия"},
+	"comedy":           {"Comedy", "Комедия"},
+	"drama":            {"Drama", "Драма"},
+	"screenplays":      {"Screenplays", "Сценарии"},
+
+	// antique literature
+	"antique_ant":      {"Antique Literature", "Античная литература"},
+	"antique_european": {"European Antique Literature", "Европейская старинная литература"},
+	"antique_russian":  {"Old Russian Literature", "Древнерусская литература"},
+	"antique_east":     {"Old East Literature", "Древневосточная литература"},
+	"antique_myths":    {"Myths, Legends, Epos", "Мифы, легенды, эпос"},
+	"antique":          {"Other Antique Literature", "Старинная литература"},
+
+	// science and education
+	"sci_history":        {"History", "История"},
+	"sci_psychology":     {"Psychology", "Психология"},
+	"sci_culture":        {"Cultural Science", "Культурология"},
+	"sci_religion":       {"Religious Studies", "Религиоведение"},
+	"sci_philosophy":     {"Philosophy", "Философия"},
+	"sci_politics":       {"Politics", "Политика"},
+	"sci_business":       {"Business Literature", "Деловая литература"},
+	"sci_juris":          {"Jurisprudence", "Юриспруденция"},
+	"sci_linguistic":     {"Linguistics", "Языкознание"},
+	"sci_medicine":       {"Medicine", "Медицина"},
+	"sci_phys":           {"Physics", "Физика"},
+	"sci_math":           {"Mathematics", "Математика"},
+	"sci_chem":           {"Chemistry", "Химия"},
+	"sci_biology":        {"Biology", "Биология"},
+	"sci_tech":           {"Technical", "Технические науки"},
+	"science":            {"Science", "Научная литература"},
+	"sci_economy":        {"Economy", "Экономика"},
+	"sci_state":          {"State and Law", "Государство и право"},
+	"sci_social_studies": {"Social Studies", "Обществознание, социология"},
+	"sci_pedagogy":       {"Pedagogy", "Педагогика"},
+	"sci_geo":            {"Geography and Geology", "Геология и география"},
+	"sci_cosmos":         {"Astronomy and Space", "Астрономия и космос"},
+	"sci_ecology":        {"Ecology", "Экология"},
+	"sci_zoo":            {"Zoology", "Зоология"},
+	"sci_botany":         {"Botany", "Ботаника"},
+	"sci_textbook":       {"Textbook", "Учебники и пособия"},
+	"military_special":   {"Military Science", "Военное дело"},
+	"military_history":   {"Military History", "Военная история"},
+
+	// computers and internet
+	"comp_www":         {"Internet", "Интернет"},
+	"comp_programming": {"Programming", "Программирование"},
+	"comp_hard":        {"Computer Hardware", "Компьютерное железо"},
+	"comp_soft":        {"Programs", "Программы"},
+	"comp_db":          {"Databases", "Базы данных"},
+	"comp_osnet":       {"OS and Networking", "ОС и сети"},
+	"computers":        {"Computers", "Компьютеры"},
+
+	// reference
+	"ref_encyc": {"Encyclopedias", "Энциклопедии"},
+	"ref_dict":  {"Dictionaries", "Словари"},
+	"ref_ref":   {"Reference", "Справочники"},
+	"ref_guide": {"Guidebooks", "Руководства"},
+	"reference": {"Other Reference", "Справочная литература"},
+
+	// nonfiction
+	"nonf_biography": {"Biography and Memoirs", "Биографии и мемуары"},
+	"nonf_publicism": {"Publicism", "Публицистика"},
+	"nonf_criticism": {"Criticism", "Критика"},
+	"design":         {"Art and Design", "Искусство и дизайн"},
+	"nonfiction":     {"Other Nonfiction", "Документальная литература"},
+	"nonf_military":  {"Military Documentary", "Военная документалистика"},
+	"travel_notes":   {"Travel Notes", "Путевые заметки"},
+
+	// religion and spirituality
+	"religion_rel":           {"Religion", "Религия"},
+	"religion_esoterics":     {"Esoterics", "Эзотерика"},
+	"religion_self":          {"Self-improvement", "Самосовершенствование"},
+	"religion":               {"Other Religion", "Религиозная литература"},
+	"religion_christianity":  {"Christianity", "Христианство"},
+	"religion_orthodoxy":     {"Orthodoxy", "Православие"},
+	"religion_catholicism":   {"Catholicism", "Католицизм"},
+	"religion_protestantism": {"Protestantism", "Протестантизм"},
+	"religion_islam":         {"Islam", "Ислам"},
+	"religion_judaism":       {"Judaism", "Иудаизм"},
+	"religion_budda":         {"Buddhism", "Буддизм"},
+	"religion_hinduism":      {"Hinduism", "Индуизм"},
+	"religion_paganism":      {"Paganism", "Язычество"},
+
+	// humor
+	"humor_anecdote": {"Anecdote", "Анекдоты"},
+	"humor_prose":    {"Humorous Prose", "Юмористическая проза"},
+	"humor_verse":    {"Humorous Verses", "Юмористические стихи"},
+	"humor":          {"Other Humor", "Юмор"},
+	"humor_satire":   {"Satire", "Сатира"},
+
+	// home and family
+	"home_cooking":    {"Cooking", "Кулинария"},
+	"home_pets":       {"Pets", "Домашние животные"},
+	"home_crafts":     {"Hobbies and Crafts", "Хобби и ремесла"},
+	"home_entertain":  {"Entertaining", "Развлечения"},
+	"home_health":     {"Health", "Здоровье"},
+	"home_garden":     {"Garden", "Сад и огород"},
+	"home_diy":        {"Do It Yourself", "Сделай сам"},
+	"home_sport":      {"Sports", "Спорт"},
+	"home_sex":        {"Erotica and Sex", "Эротика, секс"},
+	"home":            {"Other Home and Family", "Домоводство"},
+	"home_collecting": {"Collecting", "Коллекционирование"},
+
+	// economy and business
+	"economics":        {"Economics", "Экономика"},
+	"banking":          {"Banking", "Банковское дело"},
+	"accounting":       {"Accounting", "Бухучет и аудит"},
+	"global_economy":   {"Global Economy", "Внешнеэкономическая деятельность"},
+	"marketing":        {"Marketing and Advertising", "Маркетинг, PR, реклама"},
+	"management":       {"Management", "Управление, подбор персонала"},
+	"org_behavior":     {"Corporate Culture", "Корпоративная культура"},
+	"personal_finance": {"Personal Finance", "Личные финансы"},
+	"small_business":   {"Small Business", "Малый бизнес"},
+	"stock":            {"Stock Market", "Ценные бумаги, инвестиции"},
+	"industries":       {"Industries", "Отраслевые издания"},
+	"job_hunting":      {"Job Hunting", "Поиск работы, карьера"},
+	"real_estate":      {"Real Estate", "Недвижимость"},
+	"paper_work":       {"Office Work", "Делопроизводство"},
+	"popular_business": {"Popular Business", "О бизнесе популярно"},
+	"economics_ref":    {"Business Reference", "Деловая литература"},
+
+	// miscellaneous
+	"other":             {"Other", "Неотсортированное"},
+	"notes":             {"Notes", "Партитуры"},
+	"periodic":          {"Periodicals", "Журналы, газеты"},
+	"comics":            {"Comics", "Комиксы"},
+	"unfinished":        {"Unfinished", "Недописанное"},
+	"visual_arts":       {"Visual Arts", "Изобразительное искусство, фотография"},
+	"cine":              {"Cinema", "Кино"},
+	"theatre":           {"Theatre", "Театр"},
+	"music":             {"Music", "Музыка"},
+	"architecture_book": {"Architecture", "Скульптура и архитектура"},
+	"folklore":          {"Folklore", "Фольклор"},
+	"folk_tale":         {"Folk Tales", "Народные сказки"},
+	"proverbs":          {"Proverbs", "Пословицы, поговорки"},
+	"foreign_language":  {"Foreign Languages", "Иностранные языки"},
+}
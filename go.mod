@@ -3,8 +3,6 @@ module github.com/alexander-sapozhnikov/fb2text
 go 1.21
 
 require (
-	github.com/huandu/xstrings v1.5.0
 	golang.org/x/net v0.30.0
+	golang.org/x/text v0.21.0
 )
-
-require golang.org/x/text v0.21.0 // indirect
@@ -0,0 +1,119 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"maps"
+	"slices"
+)
+
+/*
+ElementHandler is a set of callbacks for an element of FB2 file, see
+WithElementHandler. Every callback returns true if it handled the element
+itself and the default handling of the parser is skipped, or false to extend
+the default handling. Nil callbacks are not called
+*/
+type ElementHandler struct {
+	// Start is called for the start tag of the element
+	Start func(ctx *ElementContext, attrs []xml.Attr) bool
+	// End is called for the end tag of the element
+	End func(ctx *ElementContext) bool
+	// CharData is called for the text directly inside the element
+	CharData func(ctx *ElementContext, text string) bool
+}
+
+/*
+ElementContext gives element handlers access to the line being parsed. It is
+valid only during the callback
+*/
+type ElementContext struct {
+	p    *parser
+	path []string
+}
+
+// Path returns the names of the parent elements of the element, starting from the root
+func (c *ElementContext) Path() []string {
+	return slices.Clone(c.path)
+}
+
+// Text returns the text of the current line parsed so far
+func (c *ElementContext) Text() string {
+	return c.p.currLine
+}
+
+// WriteText adds the text to the current line as is
+func (c *ElementContext) WriteText(text string) {
+	c.p.currLine += text
+}
+
+// SetKind changes the kind of the current line
+func (c *ElementContext) SetKind(kind Kind) {
+	c.p.currKind = kind
+}
+
+// OpenSpan starts styled part of the current line
+func (c *ElementContext) OpenSpan(style SpanStyle, target string) {
+	c.p.openSpan(style, target)
+}
+
+// CloseSpan ends the innermost styled part of the current line if it has the given style
+func (c *ElementContext) CloseSpan(style SpanStyle) {
+	c.p.closeSpan(style)
+}
+
+/*
+EmitLine ends the current line and adds it to the parsed lines, a new
+paragraph is started
+*/
+func (c *ElementContext) EmitLine() {
+	c.p.emitLine()
+}
+
+/*
+AddLine adds the line to the parsed lines after the lines emitted before. The
+current line is not changed
+*/
+func (c *ElementContext) AddLine(line Line) {
+	c.p.addLine(line)
+}
+
+/*
+WithElementHandler registers the handler for the elements with the given
+local name, e.g. "subtitle" or a vendor-specific element. It can override or
+extend the default conversion of the element to lines and markers. The
+handler is called for the elements anywhere in the file, so it should check
+ElementContext.Path if only the book text matters. The last handler for a
+name wins
+*/
+func WithElementHandler(name string, h ElementHandler) Option {
+	return func(o option) option {
+		handlers := maps.Clone(o.handlers)
+		if handlers == nil {
+			handlers = make(map[string]ElementHandler)
+		}
+		handlers[name] = h
+		o.handlers = handlers
+		return o
+	}
+}
+
+// handleStart calls the Start handler of the element, returns true if the default handling is skipped
+func (p *parser) handleStart(se xml.StartElement) bool {
+	h, ok := p.opt.handlers[se.Name.Local]
+	return ok && h.Start != nil && h.Start(&ElementContext{p: p, path: p.tags}, se.Attr)
+}
+
+// handleEnd calls the End handler of the element, returns true if the default handling is skipped
+func (p *parser) handleEnd(name string) bool {
+	h, ok := p.opt.handlers[name]
+	return ok && h.End != nil && h.End(&ElementContext{p: p, path: p.tags})
+}
+
+// handleCharData calls the CharData handler of the current element, returns true if the default handling is skipped
+func (p *parser) handleCharData(text string) bool {
+	n := len(p.tags)
+	if n == 0 {
+		return false
+	}
+	h, ok := p.opt.handlers[p.tags[n-1]]
+	return ok && h.CharData != nil && h.CharData(&ElementContext{p: p, path: p.tags[:n-1]}, text)
+}
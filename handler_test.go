@@ -0,0 +1,56 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandledElementIDs(t *testing.T) {
+	const book = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><section><p>First</p>
+<subtitle id="s1">Scene</subtitle>
+<p>Text</p>
+<v id="v1">Custom</v>
+<p><a l:href="#s1">to scene</a> <a l:href="#v1">to custom</a></p>
+</section></body>
+</FictionBook>`
+
+	verse := ElementHandler{
+		Start: func(ctx *ElementContext, _ []xml.Attr) bool {
+			ctx.SetKind(KindVerse)
+			return true
+		},
+		End: func(ctx *ElementContext) bool {
+			ctx.EmitLine()
+			return true
+		},
+	}
+	info, lines, err := ParseBookFromReader(strings.NewReader(book), ParseBody(),
+		WithTagMapping(map[string]string{"subtitle": "{{title}}"}), WithElementHandler("v", verse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{{section}}",
+		"First",
+		"{{title}}Scene",
+		"Text",
+		"{{verse}}Custom",
+		"{{anchor:s1}}to scene{{anchoroff}} {{anchor:v1}}to custom{{anchoroff}}",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines =\n%q\nwant\n%q", lines, want)
+	}
+	if anchors := map[string]int{"s1": 2, "v1": 4}; !reflect.DeepEqual(info.Anchors, anchors) {
+		t.Errorf("anchors = %v, want %v", info.Anchors, anchors)
+	}
+	ids := []ElementID{{ID: "s1", Element: "subtitle", Line: 2}, {ID: "v1", Element: "v", Line: 4}}
+	if !reflect.DeepEqual(info.IDs, ids) {
+		t.Errorf("ids = %v, want %v", info.IDs, ids)
+	}
+}
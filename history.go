@@ -0,0 +1,52 @@
+package fb2text
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*
+HistoryEntry is a record of the document history. Version is the document
+version the record describes, e.g. "1.1", or empty string if the record
+does not start with a version
+*/
+type HistoryEntry struct {
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+/*
+historyVersion matches the version at the beginning of a history record: the
+prefix, the version number, and the separator
+*/
+var historyVersion = regexp.MustCompile(`(?i)^(v|ver\.?|version|в\.|вер\.?|версия)?\s*(\d+(?:\.\d+)+|\d+)\s*([—–:)\-]?)\s*`)
+
+/*
+historyDate matches the date at the beginning of a history record, e.g.
+"2009-01-01", "12.03.2008", or "2009 - "
+*/
+var historyDate = regexp.MustCompile(`^(?:\d{4}-\d\d-\d\d|\d\d?\.\d\d?\.\d{4}|\d{4}\s*-)`)
+
+/*
+HistoryEntries returns the document history as records with versions. The
+version is taken from the beginning of every history paragraph, e.g.
+"v1.1 — fixed OCR errors" is the version "1.1" with the text "fixed OCR
+errors". Records starting with a date, e.g. "2009-01-01 — created", have no
+version. A number without dots is a version only after a prefix(v, ver,
+версия, etc.) or before a dash or a colon
+*/
+func (d DocumentInfo) HistoryEntries() []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(d.History))
+	for _, text := range d.History {
+		text = strings.TrimSpace(text)
+		entry := HistoryEntry{Text: text}
+		m := historyVersion.FindStringSubmatch(text)
+		if m != nil && !historyDate.MatchString(text) &&
+			(m[1] != "" || strings.Contains(m[2], ".") || strings.ContainsAny(m[3], "—–:-")) {
+			entry = HistoryEntry{Version: m[2], Text: text[len(m[0]):]}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
@@ -0,0 +1,59 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+/*
+imageStart handles <image> of the book text. An image inside a paragraph is
+added to the current line as an image span, other images are separate
+lines followed by a caption line if the image has a title. The text of the
+span is the alternative text of the image
+*/
+func (p *parser) imageStart(se xml.StartElement) {
+	id := strings.TrimPrefix(hrefValue(se), "#")
+	alt := attrValue(se, "alt")
+	title := attrValue(se, "title")
+
+	if n := len(p.tags); n > 0 && isText(p.tags[n-1]) {
+		if alt == "" {
+			alt = title
+		}
+		start := len(p.currLine)
+		p.currLine += alt
+		p.currSpans = append(p.currSpans, Span{Start: start, End: len(p.currLine), Style: StyleImage, Target: id})
+		return
+	}
+
+	p.emitLine()
+	p.visitImage(id, alt)
+	line := Line{
+		Kind:  KindImage,
+		Text:  alt,
+		Spans: []Span{{Start: 0, End: len(alt), Style: StyleImage, Target: id}},
+		Lang:  p.lang(),
+		Depth: p.depth(),
+	}
+	p.noteLine(line)
+	if p.opt.skipSystemLines && !p.opt.expandNotes {
+		line.Spans = nil
+	}
+	if line.Text != "" || line.Spans != nil {
+		p.addLine(line)
+	}
+	p.imageCaption(title)
+}
+
+// imageCaption adds the caption line of the image with the title
+func (p *parser) imageCaption(title string) {
+	title = collapseSpace("", title)
+	if title == "" {
+		return
+	}
+
+	line := Line{Kind: KindCaption, Text: title, Lang: p.lang(), Depth: p.depth()}
+	p.visitLine(line)
+	p.noteLine(line)
+	p.addLine(line)
+}
@@ -0,0 +1,605 @@
+package fb2text
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/*
+BookInfo is the information about FB2 book from its description: book
+title, authors, translators, sequences, genres, keywords, date, text
+language (Language) and the original book language (SourceLanguage) from
+title-info, the original book information, the document information, the
+paper edition information, custom information, and distribution rights
+*/
+type BookInfo struct {
+	Authors []Author `json:"authors"`
+	Title   string   `json:"title"`
+	// Sequence is the name of the first book series of title-info, see
+	// Sequences
+	Sequence string `json:"sequence"`
+	// Language is the book language as BCP-47 tag, see NormalizeLanguage.
+	// It is the value from the book as is if it cannot be recognized
+	Language string `json:"language"`
+	// LanguageRaw is the book language exactly as it is written in the book
+	LanguageRaw string `json:"languageRaw"`
+	// SourceLanguage is the language of the original book if it is
+	// translated, normalized the same way as Language
+	SourceLanguage string `json:"sourceLanguage"`
+	// SourceLanguageRaw is the original book language as it is written in
+	// the book
+	SourceLanguageRaw string `json:"sourceLanguageRaw"`
+	// Genres is the list of all book genres
+	Genres []Genre `json:"genres"`
+	// Genre is the last genre of the book.
+	//
+	// Deprecated: use Genres
+	Genre string `json:"-"`
+	// Annotation is the plain text of the book annotation, paragraphs are
+	// separated by new lines
+	Annotation string `json:"annotation"`
+	// AnnotationLines is the annotation as typed lines with emphasized parts
+	AnnotationLines Lines `json:"annotationLines"`
+	// Keywords is the raw content of <keywords>, see KeywordList
+	Keywords string `json:"keywords"`
+	// Date is the date the book was written
+	Date Date `json:"date"`
+	// Translators is the list of translators of a translated book
+	Translators []Author `json:"translators"`
+	// Sequences is the list of author series from title-info including nested ones
+	Sequences []Sequence `json:"sequences"`
+	// ID is the unique document identifier, the same as DocumentInfo.ID. It
+	// is used by e-book catalogs to find duplicates and updates of the book
+	ID string `json:"id"`
+	// DocumentInfo is the information about the FB2 file itself
+	DocumentInfo DocumentInfo `json:"documentInfo"`
+	// PublishInfo is the information about the paper edition of the book
+	PublishInfo PublishInfo `json:"publishInfo"`
+	// PublisherSequences is the list of publisher series from publish-info
+	PublisherSequences []Sequence `json:"publisherSequences"`
+	// Source is the information about the original book of a translation
+	Source SourceInfo `json:"source"`
+	// CustomInfo is the content of custom-info elements by their info-type
+	CustomInfo map[string][]string `json:"customInfo"`
+	// Cover is the first cover image. It is empty if the book body is not
+	// parsed
+	Cover Cover `json:"cover"`
+	// Covers is the list of all coverpage images, e.g. the front and the
+	// back cover, in the order of coverpage
+	Covers []Cover `json:"covers"`
+	// CoverIDs are the ids of the coverpage image binaries in the order of
+	// coverpage. Unlike Covers, they are known without parsing the body
+	CoverIDs []string `json:"coverIds"`
+	// Notes are the footnotes from the notes body by their ids. It is empty
+	// if the book body is not parsed
+	Notes map[string]Note `json:"notes"`
+	// Bodies are the lines of named bodies(notes, comments, etc.) by the
+	// body name. Several bodies with the same name are joined. It is empty if
+	// the book body is not parsed
+	Bodies map[string]Lines `json:"bodies"`
+	// Anchors are the indexes of the returned lines by the ids of the book
+	// elements: sections, paragraphs, notes, etc. An id points to the first
+	// line of the element. It is empty if the book body is not parsed
+	Anchors map[string]int `json:"anchors"`
+	// IDs are all ids of the book elements(sections, paragraphs, notes,
+	// images, binaries, etc.) in the order of the file, duplicates included.
+	// It is empty if the book body is not parsed
+	IDs []ElementID `json:"ids"`
+	// Outputs is the information about distribution rights of the book
+	Outputs []OutputInfo `json:"outputs"`
+	// Version is the FB2 specification version of the book. Elements of
+	// newer versions can be in the book text, so the version is exact only
+	// if the book body is parsed
+	Version FormatVersion `json:"version"`
+}
+
+/*
+MarshalJSON encodes the book information with empty lists and maps as [] and
+{} instead of null, so JSON consumers get the same shape for every book
+*/
+func (b BookInfo) MarshalJSON() ([]byte, error) {
+	type bookInfo BookInfo
+	info := emptyLists(reflect.ValueOf(b)).Interface().(BookInfo)
+
+	return json.Marshal(bookInfo(info))
+}
+
+/*
+emptyLists returns a deep copy of the value with nil slices and maps
+replaced by empty ones. Byte slices are kept as is
+*/
+func emptyLists(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(emptyLists(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(emptyLists(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), emptyLists(iter.Value()))
+		}
+		return c
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(emptyLists(v.Elem()))
+		return c
+	default:
+		return v
+	}
+}
+
+/*
+KeywordList splits Keywords by commas and returns the list of non-empty
+keywords without surrounding spaces
+*/
+func (b BookInfo) KeywordList() []string {
+	list := make([]string, 0)
+	for _, kw := range strings.Split(b.Keywords, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			list = append(list, kw)
+		}
+	}
+
+	return list
+}
+
+/*
+Genre is a book genre. Code is a genre code from FB2 genre list(e.g.
+sf_heroic), Match is the percentage of how much the book matches the genre
+*/
+type Genre struct {
+	Code  string `json:"code"`
+	Match int    `json:"match"`
+}
+
+/*
+Date is a date from FB2. Text is a human readable date, e.g. "1957" or
+"spring 1957", Value is an optional machine-readable date in ISO format
+*/
+type Date struct {
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+/*
+MarshalJSON encodes the date as an object with text, value, and year, so
+JSON consumers do not have to parse the date themselves
+*/
+func (d Date) MarshalJSON() ([]byte, error) {
+	type date Date
+	return json.Marshal(struct {
+		date
+		Year int `json:"year"`
+	}{date(d), d.Year()})
+}
+
+/*
+Year returns the year of the date. It is the first four-digit number in
+Value or, if Value does not have one, in Text. Returns 0 if there is no year
+*/
+func (d Date) Year() int {
+	for _, s := range []string{d.Value, d.Text} {
+		digits := 0
+		for i := 0; i < len(s); i++ {
+			if s[i] >= '0' && s[i] <= '9' {
+				digits++
+				continue
+			}
+			if digits == 4 {
+				year, _ := strconv.Atoi(s[i-4 : i])
+				return year
+			}
+			digits = 0
+		}
+		if digits == 4 {
+			year, _ := strconv.Atoi(s[len(s)-4:])
+			return year
+		}
+	}
+
+	return 0
+}
+
+/*
+Sequence is a book series. Number is the book number in the series or 0 if
+it is not set. Parent is the enclosing series of a nested sequence, e.g. a
+subseries of a big cycle, or nil
+*/
+type Sequence struct {
+	Name   string    `json:"name"`
+	Number int       `json:"number"`
+	Parent *Sequence `json:"parent,omitempty"`
+}
+
+/*
+DocumentInfo is the information about FB2 document: who and how created it,
+where the text was taken from, and the document version. ID and Version
+allow to find duplicates and newer revisions of the same book
+*/
+type DocumentInfo struct {
+	Authors     []Author `json:"authors"`
+	ProgramUsed string   `json:"programUsed"`
+	Date        Date     `json:"date"`
+	SrcURLs     []string `json:"srcUrls"`
+	SrcOCR      string   `json:"srcOcr"`
+	ID          string   `json:"id"`
+	Version     string   `json:"version"`
+	// History is the list of paragraphs describing the document changes
+	History []string `json:"history"`
+}
+
+/*
+SourceInfo is the information about the original book from src-title-info.
+It is empty if the book is not a translation
+*/
+type SourceInfo struct {
+	Authors  []Author `json:"authors"`
+	Title    string   `json:"title"`
+	Language string   `json:"language"`
+	Date     Date     `json:"date"`
+}
+
+/*
+PublishInfo is the information about the paper book the FB2 was made from.
+BookName is the title of the printed book, it may differ from the title in
+title-info
+*/
+type PublishInfo struct {
+	BookName  string `json:"bookName"`
+	Publisher string `json:"publisher"`
+	City      string `json:"city"`
+	Year      string `json:"year"`
+	ISBN      string `json:"isbn"`
+}
+
+// Author is a book author
+type Author struct {
+	FirstName  string   `json:"firstName"`
+	MiddleName string   `json:"middleName"`
+	LastName   string   `json:"lastName"`
+	Nickname   string   `json:"nickname"`
+	HomePages  []string `json:"homePages"`
+	Emails     []string `json:"emails"`
+	// ID is the author identifier in an online library
+	ID string `json:"id"`
+}
+
+// titleInfoStart handles the start of an element inside <title-info>
+func (p *parser) titleInfoStart(se xml.StartElement) {
+	switch se.Name.Local {
+	case "empty-line":
+		if slices.Contains(p.tags, "annotation") {
+			p.info.AnnotationLines = append(p.info.AnnotationLines, Line{Kind: KindEmpty})
+		}
+	case "date":
+		p.dateValue = attrValue(se, "value")
+	case "image":
+		id := strings.TrimPrefix(hrefValue(se), "#")
+		if p.tags[len(p.tags)-1] == "coverpage" && id != "" && !p.isCover(id) {
+			p.info.CoverIDs = append(p.info.CoverIDs, id)
+		}
+	case "sequence":
+		p.sequenceStart(se, &p.info.Sequences)
+	case "genre":
+		// the default match is 100 according to FB2 schema
+		p.genreMatch = 100
+		for _, attr := range se.Attr {
+			if attr.Name.Local != "match" {
+				continue
+			}
+			if match, err := strconv.Atoi(strings.TrimSpace(attr.Value)); err == nil {
+				p.genreMatch = match
+			} else {
+				p.warn("invalid genre match %q", attr.Value)
+			}
+		}
+	}
+}
+
+/*
+titleInfoEnd handles the end of an element inside <title-info>. tags is the
+path to the parent of the element
+*/
+func (p *parser) titleInfoEnd(name string, tags []string) {
+	binfo := &p.info
+	if name == "author" && len(tags) == 3 {
+		author := p.author
+		if author.FirstName == "" && author.MiddleName == "" &&
+			author.LastName == "" && author.Nickname == "" {
+			p.warn("author without name")
+		}
+		p.authorEnd(&binfo.Authors)
+		return
+	}
+	if name == "translator" && len(tags) == 3 {
+		p.authorEnd(&binfo.Translators)
+		return
+	}
+
+	if name == "sequence" {
+		p.sequenceEnd()
+		// the legacy field is the first top-level sequence of title-info
+		if len(tags) == 3 && binfo.Sequence == "" && len(binfo.Sequences) > 0 {
+			binfo.Sequence = binfo.Sequences[0].Name
+		}
+	}
+
+	if slices.Contains(tags, "annotation") {
+		p.annotationEnd(name)
+		return
+	}
+	if name == "annotation" && len(tags) == 3 {
+		texts := make([]string, 0, len(binfo.AnnotationLines))
+		for _, line := range binfo.AnnotationLines {
+			texts = append(texts, line.Text)
+		}
+		binfo.Annotation = strings.Join(texts, "\n")
+		return
+	}
+
+	if name == "genre" {
+		binfo.Genre = p.currLine
+		binfo.Genres = append(binfo.Genres, Genre{Code: p.currLine, Match: p.genreMatch})
+	} else if isInside(tags, "author") || isInside(tags, "translator") {
+		p.authorPart(name, p.currLine)
+	} else if name == "book-title" {
+		binfo.Title = p.currLine
+	} else if name == "lang" {
+		binfo.LanguageRaw = p.currLine
+		binfo.Language = p.language(p.currLine)
+	} else if name == "src-lang" {
+		binfo.SourceLanguageRaw = p.currLine
+		binfo.SourceLanguage = p.language(p.currLine)
+	} else if name == "keywords" {
+		binfo.Keywords = p.currLine
+	} else if name == "date" && len(tags) == 3 {
+		binfo.Date = Date{Text: p.currLine, Value: p.dateValue}
+	}
+}
+
+// documentInfoStart handles the start of an element inside <document-info>
+func (p *parser) documentInfoStart(se xml.StartElement) {
+	if se.Name.Local == "date" {
+		p.dateValue = attrValue(se, "value")
+	}
+}
+
+/*
+documentInfoEnd handles the end of an element inside <document-info>. tags
+is the path to the parent of the element
+*/
+func (p *parser) documentInfoEnd(name string, tags []string) {
+	dinfo := &p.info.DocumentInfo
+	if isInside(tags, "author") {
+		p.authorPart(name, p.currLine)
+		return
+	}
+	if name == "author" && len(tags) == 3 {
+		p.authorEnd(&dinfo.Authors)
+		return
+	}
+	if isInside(tags, "history") {
+		if name == "p" && p.currLine != "" {
+			dinfo.History = append(dinfo.History, p.currLine)
+		}
+		return
+	}
+	if len(tags) != 3 {
+		return
+	}
+
+	switch name {
+	case "program-used":
+		dinfo.ProgramUsed = p.currLine
+	case "date":
+		dinfo.Date = Date{Text: p.currLine, Value: p.dateValue}
+	case "src-url":
+		dinfo.SrcURLs = append(dinfo.SrcURLs, p.currLine)
+	case "src-ocr":
+		dinfo.SrcOCR = p.currLine
+	case "id":
+		dinfo.ID = p.currLine
+		p.info.ID = p.currLine
+	case "version":
+		dinfo.Version = p.currLine
+	}
+}
+
+// annotationEnd handles the end of an element inside title-info annotation
+func (p *parser) annotationEnd(name string) {
+	switch name {
+	case "emphasis", "strong":
+		p.closeSpan(p.emphasisStyle(name))
+	case "p", "v", "subtitle", "text-author":
+		if p.currLine != "" {
+			p.info.AnnotationLines = append(p.info.AnnotationLines, p.line())
+		}
+		p.resetLine(KindParagraph)
+	}
+}
+
+// srcTitleInfoStart handles the start of an element inside <src-title-info>
+func (p *parser) srcTitleInfoStart(se xml.StartElement) {
+	if se.Name.Local == "date" {
+		p.dateValue = attrValue(se, "value")
+	}
+}
+
+/*
+srcTitleInfoEnd handles the end of an element inside <src-title-info>. tags
+is the path to the parent of the element
+*/
+func (p *parser) srcTitleInfoEnd(name string, tags []string) {
+	src := &p.info.Source
+	if isInside(tags, "author") {
+		p.authorPart(name, p.currLine)
+		return
+	}
+	if name == "author" && len(tags) == 3 {
+		p.authorEnd(&src.Authors)
+		return
+	}
+	if len(tags) != 3 {
+		return
+	}
+
+	switch name {
+	case "book-title":
+		src.Title = p.currLine
+	case "lang":
+		src.Language = p.language(p.currLine)
+	case "date":
+		src.Date = Date{Text: p.currLine, Value: p.dateValue}
+	}
+}
+
+// publishInfoStart handles the start of an element inside <publish-info>
+func (p *parser) publishInfoStart(se xml.StartElement) {
+	if se.Name.Local == "sequence" {
+		p.sequenceStart(se, &p.info.PublisherSequences)
+	}
+}
+
+/*
+publishInfoEnd handles the end of an element inside <publish-info>. tags
+is the path to the parent of the element
+*/
+func (p *parser) publishInfoEnd(name string, tags []string) {
+	if name == "sequence" {
+		p.sequenceEnd()
+	}
+	if len(tags) != 3 {
+		return
+	}
+
+	pinfo := &p.info.PublishInfo
+	switch name {
+	case "book-name":
+		pinfo.BookName = p.currLine
+	case "publisher":
+		pinfo.Publisher = p.currLine
+	case "city":
+		pinfo.City = p.currLine
+	case "year":
+		pinfo.Year = p.currLine
+	case "isbn":
+		pinfo.ISBN = p.currLine
+	}
+}
+
+/*
+language returns the normalized language. If the language is not recognized
+the parser warns and returns it as is
+*/
+func (p *parser) language(lang string) string {
+	if tag := NormalizeLanguage(lang); tag != "" || lang == "" {
+		return tag
+	}
+	p.warn("unknown language %q", lang)
+
+	return lang
+}
+
+// customInfoEnd adds the content of <custom-info> to the book information
+func (p *parser) customInfoEnd() {
+	if p.info.CustomInfo == nil {
+		p.info.CustomInfo = make(map[string][]string)
+	}
+	p.info.CustomInfo[p.customInfoType] = append(p.info.CustomInfo[p.customInfoType], p.currLine)
+}
+
+/*
+sequenceStart adds the sequence to the list. The sequence is nested into the
+sequence being parsed, if any
+*/
+func (p *parser) sequenceStart(se xml.StartElement, list *[]Sequence) {
+	seq := &Sequence{Name: attrValue(se, "name")}
+	if n := len(p.sequenceStack); n > 0 {
+		seq.Parent = p.sequenceStack[n-1]
+	}
+	if number := strings.TrimSpace(attrValue(se, "number")); number != "" {
+		if num, err := strconv.Atoi(number); err == nil {
+			seq.Number = num
+		} else {
+			p.warn("invalid sequence number %q", number)
+		}
+	}
+	*list = append(*list, *seq)
+	p.sequenceStack = append(p.sequenceStack, seq)
+}
+
+// sequenceEnd finishes the innermost sequence being parsed
+func (p *parser) sequenceEnd() {
+	if n := len(p.sequenceStack); n > 0 {
+		p.sequenceStack = p.sequenceStack[:n-1]
+	}
+}
+
+/*
+authorPart sets the part of name or contacts of the author being parsed.
+Every part is optional, e.g. an author can have only a nickname or only a
+last name. Empty parts are ignored
+*/
+func (p *parser) authorPart(name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	author := &p.author
+	switch name {
+	case "first-name":
+		author.FirstName = value
+	case "middle-name":
+		author.MiddleName = value
+	case "last-name":
+		author.LastName = value
+	case "nickname":
+		author.Nickname = value
+	case "id":
+		author.ID = value
+	case "home-page":
+		author.HomePages = append(author.HomePages, value)
+	case "email":
+		author.Emails = append(author.Emails, value)
+	}
+}
+
+/*
+authorEnd finishes the author being parsed and adds it to the list. Elements
+of author can be in any order, so the author is collected entirely before it
+is added. Authors without any information are skipped
+*/
+func (p *parser) authorEnd(authors *[]Author) {
+	author := p.author
+	p.author = Author{}
+	if author.FirstName == "" && author.MiddleName == "" && author.LastName == "" &&
+		author.Nickname == "" && author.ID == "" && len(author.HomePages) == 0 &&
+		len(author.Emails) == 0 {
+		return
+	}
+
+	*authors = append(*authors, author)
+}
@@ -0,0 +1,42 @@
+package fb2text
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+/*
+CleanKeywords splits keywords by commas and semicolons and returns the list
+of unique tags. The tags are converted to lower case according to the rules
+of the language lang (e.g. "tr" for Turkish dotted and dotless I), spaces
+inside tags are squeezed, surrounding spaces, dots, and quotes are removed.
+The order of the first occurrence is kept
+*/
+func CleanKeywords(keywords, lang string) []string {
+	tag, _ := language.Parse(lang)
+	lower := cases.Lower(tag)
+
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, kw := range strings.FieldsFunc(keywords, func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		kw = strings.Join(strings.Fields(kw), " ")
+		kw = strings.Trim(kw, ` ."'«»“”`)
+		kw = lower.String(kw)
+		if kw == "" || seen[kw] {
+			continue
+		}
+		seen[kw] = true
+		tags = append(tags, kw)
+	}
+
+	return tags
+}
+
+// KeywordTags returns cleaned keywords of the book, see CleanKeywords
+func (b BookInfo) KeywordTags() []string {
+	return CleanKeywords(b.Keywords, b.Language)
+}
@@ -0,0 +1,56 @@
+package fb2text
+
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// nameLanguages are the languages that can be written in books by name
+var nameLanguages = []string{
+	"ru", "uk", "be", "en", "de", "fr", "es", "it", "pt", "pl", "cs", "sk",
+	"bg", "sr", "hr", "sl", "ro", "hu", "fi", "sv", "no", "da", "nl", "el",
+	"tr", "lt", "lv", "et", "ka", "hy", "az", "kk", "uz", "tt", "he", "yi",
+	"ar", "fa", "zh", "ja", "ko", "la", "eo",
+}
+
+var (
+	languageNamesOnce sync.Once
+	// languageNames maps lower case English and native language names to
+	// the language tags
+	languageNames map[string]language.Tag
+)
+
+/*
+NormalizeLanguage converts a language written in a book to BCP-47 tag, e.g.
+"ru", "ru_RU", "rus", and "Russian" become "ru" and "ru-RU". Languages
+written by name are recognized if the name is in English or in the language
+itself. Returns empty string if the language is not recognized
+*/
+func NormalizeLanguage(lang string) string {
+	lang = strings.TrimSpace(lang)
+	if lang == "" {
+		return ""
+	}
+
+	if tag, err := language.Parse(lang); err == nil {
+		return tag.String()
+	}
+
+	languageNamesOnce.Do(func() {
+		languageNames = make(map[string]language.Tag)
+		english := display.English.Languages()
+		for _, code := range nameLanguages {
+			tag := language.MustParse(code)
+			languageNames[strings.ToLower(english.Name(tag))] = tag
+			languageNames[strings.ToLower(display.Self.Name(tag))] = tag
+		}
+	})
+	if tag, ok := languageNames[strings.ToLower(lang)]; ok {
+		return tag.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,479 @@
+package fb2text
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kind is a type of a parsed line
+type Kind int
+
+const (
+	// KindParagraph is a regular paragraph of text
+	KindParagraph Kind = iota
+	// KindEmpty is an empty line
+	KindEmpty
+	// KindSection marks the start of a section, the line has no text
+	KindSection
+	// KindTitle is a line of a body or a section title
+	KindTitle
+	// KindEpigraph is a line of an epigraph
+	KindEpigraph
+	// KindEpigraphAuthor is an author of an epigraph
+	KindEpigraphAuthor
+	// KindNote is a line of a footnote inserted after the paragraph that
+	// references it, see ExpandNotes
+	KindNote
+	// KindImage is an image outside paragraphs, the text is the alternative
+	// text of the image and the line has an image span
+	KindImage
+	// KindPoem marks the start of a poem, the line has no text
+	KindPoem
+	// KindStanza marks the start of a stanza of a poem, the line has no text
+	KindStanza
+	// KindVerse is a line of a poem
+	KindVerse
+	// KindPoemTitle is a line of a poem title
+	KindPoemTitle
+	// KindPoemSubtitle is a subtitle of a poem between its stanzas
+	KindPoemSubtitle
+	// KindPoemDate is the date of a poem
+	KindPoemDate
+	// KindCite is a line of a block quotation
+	KindCite
+	// KindCiteAuthor is an author of a block quotation
+	KindCiteAuthor
+	// KindSubtitle is a subtitle inside a section, e.g. a scene break
+	KindSubtitle
+	// KindCode is a paragraph that consists of code only. The whitespace of
+	// the code is kept, the text can contain line breaks
+	KindCode
+	// KindAnnotation is a line of a section annotation
+	KindAnnotation
+	// KindPoemAuthor is an author of a poem
+	KindPoemAuthor
+	// KindStanzaTitle is a line of a stanza title
+	KindStanzaTitle
+	// KindStanzaSubtitle is a subtitle of a stanza
+	KindStanzaSubtitle
+	// KindDate is a date outside poems, e.g. the date of a letter or a diary
+	// entry
+	KindDate
+	// KindCaption is a caption of the image on the previous line, it is the
+	// title of the image
+	KindCaption
+)
+
+// kindMarkers are the internal format markers of line kinds
+var kindMarkers = map[Kind]string{
+	KindSection:        "{{section}}",
+	KindTitle:          "{{title}}",
+	KindEpigraph:       "{{epi}}",
+	KindEpigraphAuthor: "{{epiauth}}",
+	KindNote:           "{{note}}",
+	KindPoem:           "{{poem}}",
+	KindStanza:         "{{stanza}}",
+	KindVerse:          "{{verse}}",
+	KindPoemTitle:      "{{poemtitle}}",
+	KindPoemSubtitle:   "{{poemsubtitle}}",
+	KindPoemDate:       "{{poemdate}}",
+	KindCite:           "{{cite}}",
+	KindCiteAuthor:     "{{citeauth}}",
+	KindSubtitle:       "{{subtitle}}",
+	KindCode:           "{{code}}",
+	KindAnnotation:     "{{annotation}}",
+	KindPoemAuthor:     "{{poemauth}}",
+	KindStanzaTitle:    "{{stanzatitle}}",
+	KindStanzaSubtitle: "{{stanzasubtitle}}",
+	KindDate:           "{{date}}",
+	KindCaption:        "{{caption}}",
+}
+
+// SpanStyle is a style of a part of a line
+type SpanStyle int
+
+const (
+	// StyleEmphasis is emphasized text. Strong text has this style too if
+	// option CombineStrong is set
+	StyleEmphasis SpanStyle = iota
+	// StyleNote is a footnote reference, Span.Target is the note id
+	StyleNote
+	// StyleLink is a link to an external resource, Span.Target is its URL
+	StyleLink
+	// StyleAnchor is a link to an element of the book, Span.Target is the
+	// element id
+	StyleAnchor
+	// StyleImage is an image, the text is its alternative text and
+	// Span.Target is the id of the image binary
+	StyleImage
+	// StyleCode is code inside a paragraph, its whitespace is kept
+	StyleCode
+	// StyleSub is a subscript, e.g. the index in "H2O"
+	StyleSub
+	// StyleSup is a superscript, e.g. the power in "x2"
+	StyleSup
+	// StyleStrong is strong text
+	StyleStrong
+	// StyleNamed is a named style of the book, e.g. a letter or handwriting,
+	// Span.Target is the style name
+	StyleNamed
+	// StyleUnknown is the text of an element that is not a part of FB2
+	// schema, Span.Target is the element name, see WithUnknownElements
+	StyleUnknown
+)
+
+// styleNames are the names of span styles in JSON
+var styleNames = map[SpanStyle]string{
+	StyleEmphasis: "emphasis",
+	StyleNote:     "note",
+	StyleLink:     "link",
+	StyleAnchor:   "anchor",
+	StyleImage:    "image",
+	StyleCode:     "code",
+	StyleSub:      "sub",
+	StyleSup:      "sup",
+	StyleStrong:   "strong",
+	StyleNamed:    "named",
+	StyleUnknown:  "unknown",
+}
+
+// MarshalText encodes the style as its name, e.g. "emphasis"
+func (s SpanStyle) MarshalText() ([]byte, error) {
+	name, ok := styleNames[s]
+	if !ok {
+		return nil, fmt.Errorf("fb2text: unknown span style %d", int(s))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes the style from its name
+func (s *SpanStyle) UnmarshalText(text []byte) error {
+	for style, name := range styleNames {
+		if name == string(text) {
+			*s = style
+			return nil
+		}
+	}
+
+	return fmt.Errorf("fb2text: unknown span style %q", text)
+}
+
+// markers returns the internal format markers that start and end the span
+func (s Span) markers() (string, string) {
+	switch s.Style {
+	case StyleNote:
+		return "{{note:" + escapeArg(s.Target) + "}}", "{{noteoff}}"
+	case StyleLink:
+		return "{{link:" + escapeArg(s.Target) + "}}", "{{linkoff}}"
+	case StyleAnchor:
+		return "{{anchor:" + escapeArg(s.Target) + "}}", "{{anchoroff}}"
+	case StyleImage:
+		return "{{image:" + escapeArg(s.Target) + "|", "}}"
+	case StyleCode:
+		return "{{code}}", "{{codeoff}}"
+	case StyleSub:
+		return "{{sub}}", "{{suboff}}"
+	case StyleSup:
+		return "{{sup}}", "{{supoff}}"
+	case StyleStrong:
+		return "{{strongon}}", "{{strongoff}}"
+	case StyleNamed:
+		return "{{style:" + escapeArg(s.Target) + "}}", "{{styleoff}}"
+	case StyleUnknown:
+		return "{{unknown:" + escapeArg(s.Target) + "}}", "{{unknownoff}}"
+	default:
+		return "{{emon}}", "{{emoff}}"
+	}
+}
+
+// kindNames are the names of line kinds in JSON
+var kindNames = map[Kind]string{
+	KindParagraph:      "paragraph",
+	KindEmpty:          "empty",
+	KindSection:        "section",
+	KindTitle:          "title",
+	KindEpigraph:       "epigraph",
+	KindEpigraphAuthor: "epigraph-author",
+	KindNote:           "note",
+	KindImage:          "image",
+	KindPoem:           "poem",
+	KindStanza:         "stanza",
+	KindVerse:          "verse",
+	KindPoemTitle:      "poem-title",
+	KindPoemSubtitle:   "poem-subtitle",
+	KindPoemDate:       "poem-date",
+	KindCite:           "cite",
+	KindCiteAuthor:     "cite-author",
+	KindSubtitle:       "subtitle",
+	KindCode:           "code",
+	KindAnnotation:     "annotation",
+	KindPoemAuthor:     "poem-author",
+	KindStanzaTitle:    "stanza-title",
+	KindStanzaSubtitle: "stanza-subtitle",
+	KindDate:           "date",
+	KindCaption:        "caption",
+}
+
+// MarshalText encodes the kind as its name, e.g. "paragraph"
+func (k Kind) MarshalText() ([]byte, error) {
+	name, ok := kindNames[k]
+	if !ok {
+		return nil, fmt.Errorf("fb2text: unknown line kind %d", int(k))
+	}
+
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes the kind from its name
+func (k *Kind) UnmarshalText(text []byte) error {
+	for kind, name := range kindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("fb2text: unknown line kind %q", text)
+}
+
+/*
+Line is a parsed line of a book. Unlike the internal string format, the line
+kind and emphasized parts of the text are kept separately from the text, so
+there is no need to look for "{{...}}" markers in the text
+*/
+type Line struct {
+	Kind Kind   `json:"kind"`
+	Text string `json:"text"`
+	// Spans are styled parts of Text ordered by their start
+	Spans []Span `json:"spans"`
+	// Lang is the language of the line from xml:lang attribute of the line
+	// or its parents, e.g. a body or a section. It is empty if not set
+	Lang string `json:"lang"`
+	// Depth is the nesting depth of the section of the line: 0 for the lines
+	// outside sections, 1 for top-level sections, 2 for their subsections,
+	// etc. A KindSection line has the depth of the section it starts
+	Depth int `json:"depth,omitempty"`
+	// ID is the id attribute of the section started by a KindSection line or
+	// of the paragraph, verse, or subtitle of the line
+	ID string `json:"id,omitempty"`
+	// Body is the name of the body of the line, e.g. "notes" or "comments".
+	// It is empty for the main body
+	Body string `json:"body,omitempty"`
+}
+
+// Lines is a list of parsed lines
+type Lines []Line
+
+/*
+String converts the line to the internal string format, see ParseBook. The
+markers are always nested properly: if spans overlap, the inner markers are
+closed before the outer span ends and opened again after it. The braces of
+the text that could be taken for a marker are escaped, see Unescape
+*/
+func (l Line) String() string {
+	if len(l.Spans) == 0 {
+		return l.marker() + escapeText(l.Text, false)
+	}
+
+	type event struct {
+		pos int
+		on  bool
+		idx int
+		// group orders the events at the same position: the spans are
+		// closed first, then empty spans are opened and closed, then the
+		// new spans are opened
+		group int
+	}
+	events := make([]event, 0, 2*len(l.Spans))
+	for i, span := range l.Spans {
+		on, off := 2, 0
+		if span.Start == span.End {
+			on, off = 1, 1
+		}
+		events = append(events, event{pos: span.Start, on: true, idx: i, group: on},
+			event{pos: span.End, on: false, idx: i, group: off})
+	}
+	// the inner span is closed first, the outer(longer) span is opened
+	// first, otherwise the spans are ordered as they were opened
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		sa, sb := l.Spans[a.idx], l.Spans[b.idx]
+		switch {
+		case a.pos != b.pos:
+			return a.pos < b.pos
+		case a.group != b.group:
+			return a.group < b.group
+		case a.idx == b.idx:
+			return a.on
+		case a.group == 0 && sa.Start != sb.Start:
+			return sa.Start > sb.Start
+		case a.group == 0:
+			return a.idx > b.idx
+		case a.group == 2 && sa.End != sb.End:
+			return sa.End > sb.End
+		default:
+			return a.idx < b.idx
+		}
+	})
+
+	var sb strings.Builder
+	sb.WriteString(l.marker())
+	last := 0
+	// open is the stack of open spans. A span that overlaps the spans
+	// opened after it is closed after them, and they are opened again, so
+	// the markers are always nested properly
+	open := make([]int, 0, len(l.Spans))
+	// images are open, the text is the alternative text inside the marker
+	images := 0
+	for _, e := range events {
+		if images > 0 {
+			sb.WriteString(escapeArg(l.Text[last:e.pos]))
+		} else {
+			sb.WriteString(escapeText(l.Text[last:e.pos], true))
+		}
+		last = e.pos
+		on, off := l.Spans[e.idx].markers()
+		if l.Spans[e.idx].Style == StyleImage && e.on {
+			images++
+		} else if l.Spans[e.idx].Style == StyleImage {
+			images--
+		}
+		if e.on {
+			sb.WriteString(on)
+			open = append(open, e.idx)
+			continue
+		}
+
+		top := len(open) - 1
+		for top >= 0 && open[top] != e.idx {
+			top--
+		}
+		if top < 0 {
+			continue
+		}
+		inner := open[top+1:]
+		for i := len(inner) - 1; i >= 0; i-- {
+			_, innerOff := l.Spans[inner[i]].markers()
+			sb.WriteString(innerOff)
+		}
+		sb.WriteString(off)
+		for _, idx := range inner {
+			innerOn, _ := l.Spans[idx].markers()
+			sb.WriteString(innerOn)
+		}
+		open = append(open[:top], inner...)
+	}
+	sb.WriteString(escapeText(l.Text[last:], false))
+
+	return sb.String()
+}
+
+// braceMarker is the internal format marker of an escaped "{" of the text
+const braceMarker = "{{lb}}"
+
+// argEscaper escapes the characters that end the argument of a marker
+var argEscaper = strings.NewReplacer("{", braceMarker, "}", "{{rb}}", "|", "{{bar}}", "#", "{{hash}}")
+
+// unescaper restores the characters escaped by escapeText and escapeArg
+var unescaper = strings.NewReplacer(braceMarker, "{", "{{rb}}", "}", "{{bar}}", "|", "{{hash}}", "#")
+
+/*
+escapeText replaces "{" of the text with {{lb}} marker if it starts "{{".
+If marker is true, a marker follows the text, so its last "{" is replaced
+too
+*/
+func escapeText(text string, marker bool) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		last := i == len(text)-1
+		if text[i] == '{' && (last && marker || !last && text[i+1] == '{') {
+			sb.WriteString(braceMarker)
+			continue
+		}
+		sb.WriteByte(text[i])
+	}
+
+	return sb.String()
+}
+
+/*
+escapeArg escapes the argument of a marker, e.g. a link target, a section
+id, or the alternative text of an image: "{", "}", "|", and "#" are replaced
+with {{lb}}, {{rb}}, {{bar}}, and {{hash}} markers
+*/
+func escapeArg(arg string) string {
+	if !strings.ContainsAny(arg, "{}|#") {
+		return arg
+	}
+
+	return argEscaper.Replace(arg)
+}
+
+/*
+Unescape restores the characters of the book text and of the marker
+arguments escaped by the internal string format: {{lb}}, {{rb}}, {{bar}},
+and {{hash}} markers are replaced with "{", "}", "|", and "#". Call it after
+the other markers are processed, because the restored braces can look like
+markers
+*/
+func Unescape(s string) string {
+	return unescaper.Replace(s)
+}
+
+/*
+marker returns the internal format marker of the line kind. Nested sections
+have their depth in the marker, e.g. {{section:2}}, and sections with id
+have the id, e.g. {{section:2#ch1}}. Titles of bodies and
+nested sections have their depth too, e.g. {{title:0}} or {{title:2}}
+*/
+func (l Line) marker() string {
+	switch {
+	case l.Kind == KindSection && l.ID != "" && l.Depth > 1:
+		return fmt.Sprintf("{{section:%d#%s}}", l.Depth, escapeArg(l.ID))
+	case l.Kind == KindSection && l.ID != "":
+		return "{{section#" + escapeArg(l.ID) + "}}"
+	case l.Kind == KindSection && l.Depth > 1:
+		return fmt.Sprintf("{{section:%d}}", l.Depth)
+	case l.Kind == KindTitle && l.Depth != 1:
+		return fmt.Sprintf("{{title:%d}}", l.Depth)
+	default:
+		return kindMarkers[l.Kind]
+	}
+}
+
+/*
+HeadingLevel returns the level of a title line in the book structure: 1 for
+a body title, 2 for a top-level section title, 3 for a subsection title,
+etc. It can be used as HTML heading level. Returns 0 for other lines
+*/
+func (l Line) HeadingLevel() int {
+	if l.Kind != KindTitle {
+		return 0
+	}
+
+	return l.Depth + 1
+}
+
+/*
+IsBodyTitle returns true if the line is a title of a body: the title page of
+the book or of its part, e.g. the notes, rather than a section header
+*/
+func (l Line) IsBodyTitle() bool {
+	return l.Kind == KindTitle && l.Depth == 0
+}
+
+// Strings converts lines to the internal string format returned by ParseBook
+func (ls Lines) Strings() []string {
+	res := make([]string, len(ls))
+	for i, l := range ls {
+		res[i] = l.String()
+	}
+
+	return res
+}
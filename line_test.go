@@ -0,0 +1,312 @@
+package fb2text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// escapeMarkers are the markers allowed inside the argument of a marker
+var escapeMarkers = []string{"{{lb}}", "{{rb}}", "{{bar}}", "{{hash}}"}
+
+/*
+splitLine parses a line of the internal string format back: it returns the
+text without markers, the section id, and the targets of the spans
+*/
+func splitLine(s string) (text, id string, targets []string) {
+	var sb strings.Builder
+	for len(s) > 0 {
+		if !strings.HasPrefix(s, "{{") || isEscapeMarker(s) {
+			n := 1
+			if isEscapeMarker(s) {
+				n = strings.Index(s, "}}") + 2
+			}
+			sb.WriteString(s[:n])
+			s = s[n:]
+			continue
+		}
+
+		end := 2
+		for !strings.HasPrefix(s[end:], "}}") {
+			if isEscapeMarker(s[end:]) {
+				end += strings.Index(s[end:], "}}") + 2
+				continue
+			}
+			end++
+		}
+		marker := s[2:end]
+		s = s[end+2:]
+
+		name, arg, _ := strings.Cut(marker, ":")
+		switch {
+		case strings.HasPrefix(name, "section#"):
+			id = Unescape(strings.TrimPrefix(name, "section#"))
+		case name == "section" && strings.Contains(arg, "#"):
+			_, arg, _ = strings.Cut(arg, "#")
+			id = Unescape(arg)
+		case name == "image":
+			target, alt, _ := strings.Cut(arg, "|")
+			targets = append(targets, Unescape(target))
+			sb.WriteString(alt)
+		case name == "note" || name == "link" || name == "anchor":
+			targets = append(targets, Unescape(arg))
+		}
+	}
+
+	return Unescape(sb.String()), id, targets
+}
+
+// isEscapeMarker returns true if s starts with an escape marker
+func isEscapeMarker(s string) bool {
+	for _, m := range escapeMarkers {
+		if strings.HasPrefix(s, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestMarkerEscaping(t *testing.T) {
+	const book = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><section id="a}}b">
+<section id="c#d|e"><p>See <a l:href="http://x/}}y">here</a> and <a l:href="#a}}b">{{back}}</a>.</p></section>
+<image l:href="#i|1" alt="alt }} x"/>
+<p>In <image l:href="#i1" alt="{{a|b#c}}"/> text {{rb}}</p>
+</section></body>
+</FictionBook>`
+
+	_, lines, err := ParseBookLinesFromReader(strings.NewReader(book), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		line   Line
+		string string
+	}{
+		{"section id", lines[0], "{{section#a{{rb}}{{rb}}b}}"},
+		{"nested section id", lines[1], "{{section:2#c{{hash}}d{{bar}}e}}"},
+		{"link", lines[2], "See {{link:http://x/{{rb}}{{rb}}y}}here{{linkoff}} and " +
+			"{{anchor:a{{rb}}{{rb}}b}}{{lb}}{back}}{{anchoroff}}."},
+		{"image", lines[3], "{{image:i{{bar}}1|alt {{rb}}{{rb}} x}}"},
+		{"inline image", lines[4], "In {{image:i1|{{lb}}{{lb}}a{{bar}}b{{hash}}c{{rb}}{{rb}}}} text {{lb}}{rb}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.line.String()
+			if s != tt.string {
+				t.Errorf("String() = %q, want %q", s, tt.string)
+			}
+
+			text, id, targets := splitLine(s)
+			if text != tt.line.Text {
+				t.Errorf("parsed text = %q, want %q", text, tt.line.Text)
+			}
+			if tt.line.Kind == KindSection && id != tt.line.ID {
+				t.Errorf("parsed id = %q, want %q", id, tt.line.ID)
+			}
+			var want []string
+			for _, span := range tt.line.Spans {
+				want = append(want, span.Target)
+			}
+			if !reflect.DeepEqual(targets, want) {
+				t.Errorf("parsed targets = %q, want %q", targets, want)
+			}
+		})
+	}
+}
+
+func TestIsBodyTitle(t *testing.T) {
+	const book = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><title><p>Book</p></title>
+<section><title><p>One</p></title><p>Text</p>
+<section><title><p>Inner</p></title><p>Text</p></section>
+</section></body>
+<body name="notes"><title><p>Notes</p></title><section><title><p>1</p></title><p>Note</p></section></body>
+</FictionBook>`
+
+	_, lines, err := ParseBookLinesFromReader(strings.NewReader(book), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"Book": true, "One": false, "Inner": false, "Text": false, "Notes": true, "1": false, "Note": false}
+	for _, l := range lines {
+		if l.Kind == KindSection {
+			if l.IsBodyTitle() {
+				t.Errorf("section line %q is a body title", l.String())
+			}
+			continue
+		}
+		if got := l.IsBodyTitle(); got != want[l.Text] {
+			t.Errorf("IsBodyTitle(%q) = %v, want %v", l.String(), got, want[l.Text])
+		}
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		name string
+		line Line
+		want string
+	}{
+		{
+			name: "escaped braces",
+			line: Line{Text: "f({{x}})"},
+			want: "f({{lb}}{x}})",
+		},
+		{
+			name: "brace before marker",
+			line: Line{Text: "ab{", Spans: []Span{{Start: 3, End: 3, Style: StyleNote, Target: "n1"}}},
+			want: "ab{{lb}}{{note:n1}}{{noteoff}}",
+		},
+		{
+			name: "single brace",
+			line: Line{Text: "a { b"},
+			want: "a { b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if len(tt.line.Spans) == 0 {
+				text := strings.TrimPrefix(tt.line.String(), tt.line.marker())
+				if got := Unescape(text); got != tt.line.Text {
+					t.Errorf("Unescape() = %q, want %q", got, tt.line.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestSectionID(t *testing.T) {
+	tests := []struct {
+		name string
+		line Line
+		want string
+	}{
+		{
+			name: "section id",
+			line: Line{Kind: KindSection, Depth: 1, ID: "ch1"},
+			want: "{{section#ch1}}",
+		},
+		{
+			name: "nested section id",
+			line: Line{Kind: KindSection, Depth: 2, ID: "ch1.1"},
+			want: "{{section:2#ch1.1}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionDepth(t *testing.T) {
+	_, lines, err := ParseBookLinesFromReader(strings.NewReader(notesBook), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var depths []int
+	for _, l := range lines {
+		if l.Kind == KindSection {
+			depths = append(depths, l.Depth)
+		}
+	}
+	if want := []int{1, 2, 1}; !reflect.DeepEqual(depths, want) {
+		t.Errorf("section depths = %v, want %v", depths, want)
+	}
+
+	tests := []struct {
+		line Line
+		want string
+	}{
+		{Line{Kind: KindSection, Depth: 1}, "{{section}}"},
+		{Line{Kind: KindSection, Depth: 3}, "{{section:3}}"},
+	}
+	for _, tt := range tests {
+		if got := tt.line.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSpanNesting(t *testing.T) {
+	tests := []struct {
+		name string
+		line Line
+		want string
+	}{
+		{
+			name: "nested spans",
+			line: Line{Text: "note", Spans: []Span{
+				{Start: 0, End: 4, Style: StyleNote, Target: "n1"},
+				{Start: 0, End: 4, Style: StyleEmphasis},
+			}},
+			want: "{{note:n1}}{{emon}}note{{emoff}}{{noteoff}}",
+		},
+		{
+			name: "overlapping spans",
+			line: Line{Text: "abcdef", Spans: []Span{
+				{Start: 0, End: 4, Style: StyleEmphasis},
+				{Start: 2, End: 6, Style: StyleStrong},
+			}},
+			want: "{{emon}}ab{{strongon}}cd{{strongoff}}{{emoff}}{{strongon}}ef{{strongoff}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineString(t *testing.T) {
+	tests := []struct {
+		name string
+		line Line
+		want string
+	}{
+		{
+			name: "plain text",
+			line: Line{Kind: KindParagraph, Text: "text"},
+			want: "text",
+		},
+		{
+			name: "kind marker",
+			line: Line{Kind: KindVerse, Text: "verse"},
+			want: "{{verse}}verse",
+		},
+		{
+			name: "epigraph author",
+			line: Line{Kind: KindEpigraphAuthor, Text: "Author"},
+			want: "{{epiauth}}Author",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
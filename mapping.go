@@ -0,0 +1,139 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+)
+
+// mappedStyles are the span styles that an element can be mapped to by WithTagMapping
+var mappedStyles = []SpanStyle{StyleEmphasis, StyleStrong, StyleCode, StyleSub, StyleSup}
+
+/*
+WithTagMapping changes the markers of the elements of the book text. The keys
+are element names, the values are the markers: a line marker for paragraph
+elements, e.g. "{{title}}" for <subtitle>, a start marker of a span for inline
+elements, e.g. "{{emon}}" for <strong>, or an empty string to drop the
+marker, so <strong> becomes plain text and <subtitle> a regular paragraph.
+Supported span markers are {{emon}}, {{strongon}}, {{code}}, {{sub}}, and
+{{sup}}. Handlers of WithElementHandler take precedence over the mapping
+*/
+func WithTagMapping(mapping map[string]string) Option {
+	return func(o option) option {
+		if o.tagMapping == nil {
+			o.tagMapping = make(map[string]string, len(mapping))
+		} else {
+			o.tagMapping = maps.Clone(o.tagMapping)
+		}
+		maps.Copy(o.tagMapping, mapping)
+		return o
+	}
+}
+
+// mappedStyle returns the span style of the marker
+func mappedStyle(marker string) (SpanStyle, bool) {
+	for _, style := range mappedStyles {
+		if on, _ := (Span{Style: style}).markers(); on == marker {
+			return style, true
+		}
+	}
+
+	return 0, false
+}
+
+// mappedKind returns the line kind of the marker, the empty marker is a paragraph
+func mappedKind(marker string) (Kind, bool) {
+	if marker == "" {
+		return KindParagraph, true
+	}
+	for kind, m := range kindMarkers {
+		if m == marker {
+			return kind, true
+		}
+	}
+
+	return 0, false
+}
+
+// validateMapping checks that all markers of WithTagMapping are supported
+func validateMapping(mapping map[string]string) error {
+	for name, marker := range mapping {
+		if _, ok := mappedStyle(marker); ok {
+			continue
+		}
+		if _, ok := mappedKind(marker); !ok {
+			return fmt.Errorf("%w: unsupported marker %q for <%s>", ErrInvalidOption, marker, name)
+		}
+	}
+
+	return nil
+}
+
+/*
+mappingHandlers returns the element handlers with the handlers of the tag
+mapping added. Inline elements are mapped to spans, other elements to lines
+*/
+func (o option) mappingHandlers() map[string]ElementHandler {
+	if len(o.tagMapping) == 0 {
+		return o.handlers
+	}
+
+	handlers := maps.Clone(o.handlers)
+	if handlers == nil {
+		handlers = make(map[string]ElementHandler, len(o.tagMapping))
+	}
+	for name, marker := range o.tagMapping {
+		if _, ok := handlers[name]; ok {
+			continue
+		}
+		inline := isInline(name) || name == "emphasis" || name == "strong"
+		if style, ok := mappedStyle(marker); ok || inline && marker == "" {
+			handlers[name] = spanHandler(style, marker != "")
+		} else if kind, ok := mappedKind(marker); ok {
+			handlers[name] = lineHandler(kind)
+		}
+	}
+
+	return handlers
+}
+
+// spanHandler returns the handler that marks the text of an inline element with the style
+func spanHandler(style SpanStyle, styled bool) ElementHandler {
+	return ElementHandler{
+		Start: func(ctx *ElementContext, _ []xml.Attr) bool {
+			if !isInBookContent(ctx.path) {
+				return false
+			}
+			if styled {
+				ctx.OpenSpan(style, "")
+			}
+			return true
+		},
+		End: func(ctx *ElementContext) bool {
+			if !isInBookContent(ctx.path) {
+				return false
+			}
+			if styled {
+				ctx.CloseSpan(style)
+			}
+			return true
+		},
+	}
+}
+
+/*
+lineHandler returns the handler that makes the element a line of the kind.
+The line ends with the element as usual
+*/
+func lineHandler(kind Kind) ElementHandler {
+	return ElementHandler{
+		Start: func(ctx *ElementContext, _ []xml.Attr) bool {
+			if !isInBookContent(ctx.path) {
+				return false
+			}
+			ctx.EmitLine()
+			ctx.SetKind(kind)
+			return true
+		},
+	}
+}
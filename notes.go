@@ -0,0 +1,299 @@
+package fb2text
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+Note is a footnote from the notes body. ID is the id of the note section
+that note references point to, Title is the note title(usually its number),
+Lines are the note text
+*/
+type Note struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Lines Lines  `json:"lines"`
+}
+
+/*
+Preview returns the first sentence of the note text for a status bar or a
+tooltip. A sentence ends with ".", "!", "?", or "…" followed by a capital
+letter, periods after abbreviations (e.g. "Mr. Smith") are skipped. If
+maxRunes is positive, the preview is cut to maxRunes characters at a word
+boundary and ends with "…"
+*/
+func (n Note) Preview(maxRunes int) string {
+	texts := make([]string, 0, len(n.Lines))
+	for _, line := range n.Lines {
+		if line.Text != "" {
+			texts = append(texts, line.Text)
+		}
+	}
+	text := []rune(strings.Join(texts, " "))
+	for i := range text {
+		if sentenceEnd(text, i) {
+			text = text[:i+1]
+			break
+		}
+	}
+	if maxRunes <= 0 || len(text) <= maxRunes {
+		return string(text)
+	}
+
+	text = text[:maxRunes-1]
+	if i := strings.LastIndexFunc(string(text), unicode.IsSpace); i > 0 {
+		text = []rune(string(text)[:i])
+	}
+
+	return strings.TrimRightFunc(string(text), unicode.IsSpace) + "…"
+}
+
+/*
+abbreviations are the common abbreviations of notes that end with a period,
+lowercase and without the last period
+*/
+var abbreviations = map[string]bool{
+	// English
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"jr": true, "sr": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"cf": true, "ed": true, "eds": true, "vol": true, "no": true, "p": true,
+	"pp": true, "ch": true, "fig": true, "approx": true, "transl": true,
+	// Russian
+	"г": true, "гг": true, "в": true, "вв": true, "т": true, "с": true,
+	"см": true, "ср": true, "стр": true, "им": true, "ул": true, "д": true,
+	"др": true, "проч": true, "напр": true, "прим": true, "ред": true,
+	"пер": true, "изд": true, "авт": true, "ок": true, "тыс": true,
+	"млн": true, "млрд": true, "руб": true, "коп": true, "англ": true,
+	"лат": true, "нем": true, "фр": true, "франц": true, "греч": true,
+	"букв": true, "род": true, "ум": true, "акад": true, "проф": true,
+	"ст": true, "т.е": true, "т.д": true, "т.п": true, "т.к": true,
+	"н.э": true,
+}
+
+/*
+sentenceEnd returns true if the sentence ends at the rune i of the text: it is
+a punctuation mark followed by spaces and a capital letter. A period after
+an abbreviation, e.g. "Mr." or "см.", or after an initial, e.g. "A. Smith",
+does not end the sentence
+*/
+func sentenceEnd(text []rune, i int) bool {
+	if !strings.ContainsRune(".!?…", text[i]) {
+		return false
+	}
+
+	next := i + 1
+	for next < len(text) && unicode.IsSpace(text[next]) {
+		next++
+	}
+	if next == i+1 || next == len(text) || !unicode.IsUpper(text[next]) {
+		return false
+	}
+	if text[i] != '.' {
+		return true
+	}
+
+	start := i
+	for start > 0 && !unicode.IsSpace(text[start-1]) {
+		start--
+	}
+	word := []rune(strings.TrimLeftFunc(string(text[start:i]), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+	if len(word) == 1 && unicode.IsUpper(word[0]) {
+		return false
+	}
+
+	return !abbreviations[strings.ToLower(string(word))]
+}
+
+/*
+NotePreview returns the preview of the note with the id, see Note.Preview.
+It returns false if the book has no such note, e.g. the book body is not
+parsed
+*/
+func (b BookInfo) NotePreview(id string, maxRunes int) (string, bool) {
+	note, ok := b.Notes[id]
+	if !ok {
+		return "", false
+	}
+
+	return note.Preview(maxRunes), true
+}
+
+// notesBody is the name of the body with footnotes
+const notesBody = "notes"
+
+// noteStart starts collecting the note with the given id
+func (p *parser) noteStart(id string) {
+	p.note = &Note{ID: id}
+}
+
+// noteEnd adds the collected note to the book information
+func (p *parser) noteEnd() {
+	note := p.note
+	p.note = nil
+	if note.ID == "" {
+		p.warn("note without id")
+		return
+	}
+
+	if p.info.Notes == nil {
+		p.info.Notes = make(map[string]Note)
+	}
+	p.info.Notes[note.ID] = *note
+	p.noteIDs = append(p.noteIDs, note.ID)
+}
+
+// noteLine adds the line to the note being collected, if any
+func (p *parser) noteLine(line Line) {
+	note := p.note
+	if note == nil {
+		return
+	}
+
+	line.Body = p.body
+	switch line.Kind {
+	case KindSection, KindPoem, KindStanza:
+		// nested sections and poems are a part of the note text
+	case KindTitle:
+		note.Title = strings.TrimSpace(note.Title + " " + line.Text)
+	default:
+		note.Lines = append(note.Lines, line)
+	}
+}
+
+/*
+releaseNotes adds the notes to the parsed lines when the book is parsed:
+either the held lines with the notes expanded or the endnotes section. The
+note lines count for MaxLines, the lines over the limit are dropped
+*/
+func (p *parser) releaseNotes() {
+	var lines Lines
+	switch {
+	case p.opt.expandNotes:
+		lines = p.expandNotes()
+		p.held = nil
+	case p.opt.endNotes:
+		lines = p.endNotes()
+	default:
+		return
+	}
+
+	if limit := p.opt.maxLines; limit > 0 && p.count > limit {
+		lines = lines[:len(lines)-(p.count-limit)]
+		p.count = limit
+		for id, idx := range p.info.Anchors {
+			if idx >= limit {
+				delete(p.info.Anchors, id)
+			}
+		}
+	}
+	if p.opt.skipSystemLines {
+		for i := range lines {
+			lines[i].Spans = nil
+		}
+	}
+	p.lines = append(p.lines, lines...)
+}
+
+/*
+expandNotes inserts the notes after the held lines that reference them. The
+anchors are moved to the new positions of the lines, the anchor of a note
+points to its first insertion
+*/
+func (p *parser) expandNotes() Lines {
+	lines := p.held
+	res := make(Lines, 0, len(lines))
+	moved := make([]int, len(lines))
+	notes := make(map[string]int)
+	for i, line := range lines {
+		moved[i] = len(res)
+		res = append(res, line)
+		for _, span := range line.Spans {
+			note, ok := p.info.Notes[span.Target]
+			if !ok || span.Style != StyleNote {
+				continue
+			}
+			if _, ok := notes[note.ID]; !ok {
+				notes[note.ID] = len(res)
+			}
+			res = append(res, noteLines(note)...)
+		}
+	}
+
+	for id, idx := range p.info.Anchors {
+		if idx < len(moved) {
+			p.info.Anchors[id] = moved[idx]
+		}
+	}
+	for id, idx := range notes {
+		p.setAnchor(id, idx)
+	}
+	p.count = len(res)
+
+	return res
+}
+
+/*
+endNotes returns the generated notes section: the notes referenced in the
+text in the order of the first reference, then the notes that are not
+referenced in the order of the notes body
+*/
+func (p *parser) endNotes() Lines {
+	var lines Lines
+	if !p.opt.skipSystemLines {
+		lines = append(lines, Line{Kind: KindSection, Depth: 1, Body: notesBody})
+	}
+	lines = append(lines, Line{Kind: KindTitle, Text: "Notes", Depth: 1, Body: notesBody})
+	header := len(lines)
+
+	ids := append(p.noteRefs, p.noteIDs...)
+	added := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		note, ok := p.info.Notes[id]
+		if !ok || added[id] {
+			continue
+		}
+		added[id] = true
+		p.setAnchor(id, p.count+len(lines))
+		lines = append(lines, noteLines(note)...)
+	}
+	if len(lines) == header {
+		return nil
+	}
+	p.count += len(lines)
+
+	return lines
+}
+
+/*
+noteLines returns the note text as KindNote lines. The first line starts
+with the note title in brackets
+*/
+func noteLines(note Note) Lines {
+	lines := make(Lines, len(note.Lines))
+	for i, line := range note.Lines {
+		line.Kind = KindNote
+		if i == 0 && note.Title != "" {
+			line = prefixLine(line, "["+strings.Trim(note.Title, "[]")+"] ")
+		}
+		lines[i] = line
+	}
+
+	return lines
+}
+
+// prefixLine returns the line with the prefix added to its text
+func prefixLine(line Line, prefix string) Line {
+	spans := make([]Span, len(line.Spans))
+	for i, span := range line.Spans {
+		span.Start += len(prefix)
+		span.End += len(prefix)
+		spans[i] = span
+	}
+	line.Text = prefix + line.Text
+	line.Spans = spans
+
+	return line
+}
@@ -0,0 +1,201 @@
+package fb2text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNotePreview(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{"This is the note text. Second sentence.", 0, "This is the note text."},
+		{"I did it. Then I left.", 0, "I did it."},
+		{"Он ушёл. Потом вернулся.", 0, "Он ушёл."},
+		{"Written in 1999. Later revised.", 0, "Written in 1999."},
+		{`He said "yes". Then left.`, 0, `He said "yes".`},
+		{"See (the end). Then go on.", 0, "See (the end)."},
+		{"Really? Yes.", 0, "Really?"},
+		{"Mr. Smith came. Then left.", 0, "Mr. Smith came."},
+		{"A. S. Pushkin wrote it. Later.", 0, "A. S. Pushkin wrote it."},
+		{"Прим. ред. Это цитата. Дальше.", 0, "Прим. ред. Это цитата."},
+		{"См. Т. 2. Там подробнее.", 0, "См. Т. 2."},
+		{"см. стр. 5 и далее", 0, "см. стр. 5 и далее"},
+		{"e.g. Smith. Then.", 0, "e.g. Smith."},
+		{"A sentence. without a capital", 0, "A sentence. without a capital"},
+		{"This is the note text. Second sentence.", 10, "This is…"},
+		{"Short.", 10, "Short."},
+		{"", 0, ""},
+	}
+
+	for _, tt := range tests {
+		note := Note{Lines: Lines{{Kind: KindParagraph, Text: tt.text}}}
+		if got := note.Preview(tt.maxRunes); got != tt.want {
+			t.Errorf("Preview(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+		}
+	}
+}
+
+func TestNotesMaxLines(t *testing.T) {
+	const book = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><section><p>One<a l:href="#n1" type="note">1</a></p><p>Two<a l:href="#n2" type="note">2</a></p></section></body>
+<body name="notes"><section id="n1"><p>First.</p></section><section id="n2"><p>Second.</p></section></body>
+</FictionBook>`
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		lines   []string
+		anchors map[string]int
+	}{
+		{
+			name: "expanded notes",
+			opts: []Option{ExpandNotes(), MaxLines(4)},
+			lines: []string{
+				"{{section}}",
+				"One{{note:n1}}1{{noteoff}}",
+				"{{note}}First.",
+				"Two{{note:n2}}2{{noteoff}}",
+			},
+			anchors: map[string]int{"n1": 2},
+		},
+		{
+			name: "endnotes",
+			opts: []Option{EndNotes(), MaxLines(5)},
+			lines: []string{
+				"{{section}}",
+				"One{{note:n1}}1{{noteoff}}",
+				"Two{{note:n2}}2{{noteoff}}",
+				"{{section}}",
+				"{{title}}Notes",
+			},
+			anchors: map[string]int{},
+		},
+		{
+			name: "limit in the text",
+			opts: []Option{ExpandNotes(), MaxLines(2)},
+			lines: []string{
+				"{{section}}",
+				"One{{note:n1}}1{{noteoff}}",
+			},
+			anchors: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{ParseBody()}, tt.opts...)
+			info, lines, err := ParseBookFromReader(strings.NewReader(book), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines =\n%q\nwant\n%q", lines, tt.lines)
+			}
+			anchors := info.Anchors
+			if anchors == nil {
+				anchors = map[string]int{}
+			}
+			if !reflect.DeepEqual(anchors, tt.anchors) {
+				t.Errorf("anchors = %v, want %v", anchors, tt.anchors)
+			}
+		})
+	}
+}
+
+// notesBook has a body title, nested sections, an epigraph, styled text,
+// braces that look like a marker, and a footnote
+const notesBook = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0" xmlns:l="http://www.w3.org/1999/xlink">
+<description><title-info><book-title>Notes</book-title></title-info></description>
+<body><title><p>Book</p></title>
+<section id="ch1"><title><p>One</p></title>
+<epigraph><p>Epi</p><text-author>Author</text-author></epigraph>
+<p>A <emphasis>big</emphasis> word<a l:href="#n1" type="note">1</a> here.</p>
+<p>Code {{x}} <strong>bold</strong></p>
+<empty-line/>
+<section><p>Inner</p></section>
+</section></body>
+<body name="notes"><section id="n1"><title><p>1</p></title><p>Note text.</p></section></body>
+</FictionBook>`
+
+func TestNotes(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		lines []string
+	}{
+		{
+			name: "notes body",
+			opts: []Option{ParseBody()},
+			lines: []string{
+				"{{title:0}}Book",
+				"{{section#ch1}}",
+				"{{title}}One",
+				"{{epi}}Epi",
+				"{{epiauth}}Author",
+				"A {{emon}}big{{emoff}} word{{note:n1}}1{{noteoff}} here.",
+				"Code {{lb}}{x}} {{strongon}}bold{{strongoff}}",
+				"",
+				"{{section:2}}",
+				"Inner",
+				"{{section#n1}}",
+				"{{title}}1",
+				"Note text.",
+			},
+		},
+		{
+			name: "expanded notes",
+			opts: []Option{ParseBody(), ExpandNotes()},
+			lines: []string{
+				"{{title:0}}Book",
+				"{{section#ch1}}",
+				"{{title}}One",
+				"{{epi}}Epi",
+				"{{epiauth}}Author",
+				"A {{emon}}big{{emoff}} word{{note:n1}}1{{noteoff}} here.",
+				"{{note}}[1] Note text.",
+				"Code {{lb}}{x}} {{strongon}}bold{{strongoff}}",
+				"",
+				"{{section:2}}",
+				"Inner",
+			},
+		},
+		{
+			name: "endnotes",
+			opts: []Option{ParseBody(), EndNotes()},
+			lines: []string{
+				"{{title:0}}Book",
+				"{{section#ch1}}",
+				"{{title}}One",
+				"{{epi}}Epi",
+				"{{epiauth}}Author",
+				"A {{emon}}big{{emoff}} word{{note:n1}}1{{noteoff}} here.",
+				"Code {{lb}}{x}} {{strongon}}bold{{strongoff}}",
+				"",
+				"{{section:2}}",
+				"Inner",
+				"{{section}}",
+				"{{title}}Notes",
+				"{{note}}[1] Note text.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lines, err := ParseBookFromReader(strings.NewReader(notesBook), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("lines =\n%q\nwant\n%q", lines, tt.lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package fb2text
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type opfPackage struct {
+	XMLName  xml.Name    `xml:"package"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	UniqueID string      `xml:"unique-identifier,attr"`
+	Version  string      `xml:"version,attr"`
+	Metadata opfMetadata `xml:"metadata"`
+	Guide    *opfGuide   `xml:"guide,omitempty"`
+}
+
+type opfMetadata struct {
+	XmlnsDC     string          `xml:"xmlns:dc,attr"`
+	XmlnsOPF    string          `xml:"xmlns:opf,attr"`
+	Identifiers []opfIdentifier `xml:"dc:identifier"`
+	Title       string          `xml:"dc:title"`
+	Creators    []opfCreator    `xml:"dc:creator"`
+	Contributor []opfCreator    `xml:"dc:contributor"`
+	Publisher   string          `xml:"dc:publisher,omitempty"`
+	Date        string          `xml:"dc:date,omitempty"`
+	Description string          `xml:"dc:description,omitempty"`
+	Language    string          `xml:"dc:language,omitempty"`
+	Subjects    []string        `xml:"dc:subject"`
+	Meta        []opfMeta       `xml:"meta"`
+}
+
+type opfIdentifier struct {
+	ID     string `xml:"id,attr,omitempty"`
+	Scheme string `xml:"opf:scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type opfCreator struct {
+	Role   string `xml:"opf:role,attr"`
+	FileAs string `xml:"opf:file-as,attr,omitempty"`
+	Name   string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
+}
+
+type opfGuide struct {
+	References []opfReference `xml:"reference"`
+}
+
+type opfReference struct {
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr"`
+	Href  string `xml:"href,attr"`
+}
+
+/*
+WriteOPF writes the book information to w as metadata.opf file that Calibre
+understands: title, authors, translators, series with index, language,
+genres and keywords as tags, ISBN, publisher, annotation, and the cover
+reference. The cover is referenced by its binary id, e.g. "cover.jpg", so
+the caller should save Cover.Data to the file with this name next to the
+OPF file. The package identifier is the document id, the ISBN, or a UUID
+made from the book description if the book has neither
+*/
+func WriteOPF(w io.Writer, info BookInfo) error {
+	meta := opfMetadata{
+		XmlnsDC:     "http://purl.org/dc/elements/1.1/",
+		XmlnsOPF:    "http://www.idpf.org/2007/opf",
+		Title:       info.Title,
+		Publisher:   info.PublishInfo.Publisher,
+		Date:        isoDate(info.Date),
+		Description: info.Annotation,
+		Language:    info.Language,
+	}
+
+	// Calibre requires the package identifier
+	unique := opfIdentifier{ID: "fb2_id", Scheme: "FB2", Value: info.ID}
+	switch {
+	case info.ID != "":
+	case info.PublishInfo.ISBN != "":
+		unique.Scheme, unique.Value = "ISBN", info.PublishInfo.ISBN
+	default:
+		unique.Scheme, unique.Value = "uuid", descriptionUUID(info)
+	}
+	meta.Identifiers = append(meta.Identifiers, unique)
+	if info.PublishInfo.ISBN != "" && unique.Scheme != "ISBN" {
+		meta.Identifiers = append(meta.Identifiers, opfIdentifier{Scheme: "ISBN", Value: info.PublishInfo.ISBN})
+	}
+
+	for _, a := range info.Authors {
+		meta.Creators = append(meta.Creators, opfCreator{Role: "aut", FileAs: opfFileAs(a), Name: a.DisplayName(FirstLast)})
+	}
+	for _, a := range info.Translators {
+		meta.Contributor = append(meta.Contributor, opfCreator{Role: "trl", FileAs: opfFileAs(a), Name: a.DisplayName(FirstLast)})
+	}
+
+	for _, g := range info.Genres {
+		meta.Subjects = append(meta.Subjects, g.DisplayName("en"))
+	}
+	meta.Subjects = append(meta.Subjects, info.KeywordTags()...)
+
+	for _, seq := range info.Sequences {
+		if seq.Parent != nil {
+			continue
+		}
+		meta.Meta = append(meta.Meta, opfMeta{Name: "calibre:series", Content: seq.Name})
+		if seq.Number > 0 {
+			meta.Meta = append(meta.Meta, opfMeta{Name: "calibre:series_index", Content: strconv.Itoa(seq.Number)})
+		}
+		// Calibre supports only one series
+		break
+	}
+
+	pkg := opfPackage{
+		Xmlns:    "http://www.idpf.org/2007/opf",
+		UniqueID: "fb2_id",
+		Version:  "2.0",
+		Metadata: meta,
+	}
+	if len(info.Covers) > 0 {
+		pkg.Guide = &opfGuide{References: []opfReference{
+			{Type: "cover", Title: "Cover", Href: info.Covers[0].ID},
+		}}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(pkg); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+// opfFileAs returns the author name for sorting, e.g. "Efremov, Ivan"
+func opfFileAs(a Author) string {
+	first := joinNonEmpty([]string{a.FirstName, a.MiddleName}, " ")
+	if a.LastName == "" || first == "" {
+		return ""
+	}
+
+	return a.LastName + ", " + first
+}
+
+// isoDate returns the date in ISO format if it is known or the year
+func isoDate(d Date) string {
+	if d.Value != "" {
+		return d.Value
+	}
+	if year := d.Year(); year > 0 {
+		return strconv.Itoa(year)
+	}
+
+	return ""
+}
+
+/*
+descriptionUUID returns a name-based(version 5) UUID URN made from the book
+description: the title, authors, translators, series, language, date,
+annotation, and paper edition. The same description always has the same
+UUID, the book text and binaries do not change it
+*/
+func descriptionUUID(info BookInfo) string {
+	data, _ := json.Marshal([]any{
+		info.Title, info.Authors, info.Translators, info.Sequences, info.Language,
+		info.Date, info.Annotation, info.PublishInfo,
+	})
+	sum := sha1.Sum(data)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
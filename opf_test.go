@@ -0,0 +1,119 @@
+package fb2text
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteOPF(t *testing.T) {
+	info := BookInfo{
+		ID:          "id-1",
+		Title:       "Andromeda",
+		Authors:     []Author{{FirstName: "Ivan", MiddleName: "A.", LastName: "Efremov"}},
+		Translators: []Author{{Nickname: "tr"}},
+		Genres:      []Genre{{Code: "sf"}},
+		Keywords:    "space, future",
+		Language:    "ru",
+		Date:        Date{Text: "1957"},
+		Annotation:  "A novel",
+		Sequences:   []Sequence{{Name: "Great Ring", Number: 1}},
+		PublishInfo: PublishInfo{Publisher: "Molodaya Gvardiya", ISBN: "978-5"},
+		Covers:      []Cover{{ID: "cover.jpg"}},
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="fb2_id" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier id="fb2_id" opf:scheme="FB2">id-1</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">978-5</dc:identifier>
+    <dc:title>Andromeda</dc:title>
+    <dc:creator opf:role="aut" opf:file-as="Efremov, Ivan A.">Ivan A. Efremov</dc:creator>
+    <dc:contributor opf:role="trl">tr</dc:contributor>
+    <dc:publisher>Molodaya Gvardiya</dc:publisher>
+    <dc:date>1957</dc:date>
+    <dc:description>A novel</dc:description>
+    <dc:language>ru</dc:language>
+    <dc:subject>Science Fiction</dc:subject>
+    <dc:subject>space</dc:subject>
+    <dc:subject>future</dc:subject>
+    <meta name="calibre:series" content="Great Ring"></meta>
+    <meta name="calibre:series_index" content="1"></meta>
+  </metadata>
+  <guide>
+    <reference type="cover" title="Cover" href="cover.jpg"></reference>
+  </guide>
+</package>
+`
+
+	var buf bytes.Buffer
+	if err := WriteOPF(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("WriteOPF() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// opfIdentifiers returns the identifier elements written by WriteOPF
+func opfIdentifiers(t *testing.T, info BookInfo) []string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := WriteOPF(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+
+	return regexp.MustCompile(`<dc:identifier[^>]*>[^<]*</dc:identifier>`).FindAllString(buf.String(), -1)
+}
+
+func TestOPFIdentifier(t *testing.T) {
+	uuid := regexp.MustCompile(`^<dc:identifier id="fb2_id" opf:scheme="uuid">` +
+		`urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}</dc:identifier>$`)
+
+	tests := []struct {
+		name string
+		info BookInfo
+		want []string
+	}{
+		{
+			name: "document id",
+			info: BookInfo{ID: "id-1", Title: "T"},
+			want: []string{`<dc:identifier id="fb2_id" opf:scheme="FB2">id-1</dc:identifier>`},
+		},
+		{
+			name: "isbn",
+			info: BookInfo{Title: "T", PublishInfo: PublishInfo{ISBN: "978-5"}},
+			want: []string{`<dc:identifier id="fb2_id" opf:scheme="ISBN">978-5</dc:identifier>`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := opfIdentifiers(t, tt.info); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("identifiers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("uuid", func(t *testing.T) {
+		info := BookInfo{Title: "T", Authors: []Author{{LastName: "A"}}}
+		ids := opfIdentifiers(t, info)
+		if len(ids) != 1 || !uuid.MatchString(ids[0]) {
+			t.Fatalf("identifiers = %q, want a UUID", ids)
+		}
+
+		// the text and the binaries of the book do not change the UUID
+		parsed := info
+		parsed.Cover = Cover{ID: "cover.jpg", Data: []byte{1}}
+		parsed.Anchors = map[string]int{"ch1": 0}
+		if same := opfIdentifiers(t, parsed); same[0] != ids[0] {
+			t.Errorf("UUID of the parsed book = %q, want %q", same[0], ids[0])
+		}
+
+		for _, other := range []BookInfo{{Title: "T"}, {Title: "", Authors: info.Authors}} {
+			if id := opfIdentifiers(t, other); id[0] == ids[0] {
+				t.Errorf("UUID of %+v is the same as of %+v", other, info)
+			}
+		}
+	})
+}
@@ -1,24 +1,321 @@
 package fb2text
 
+import (
+	"context"
+	"fmt"
+)
+
 type option struct {
-	parseBody       bool
-	skipSystemLines bool
+	parseBody          bool
+	skipSystemLines    bool
+	size               int64
+	zipEntry           string
+	zipEntryIndex      int
+	zipEntryIndexIsSet bool
+	maxLines           int
+	maxBytes           int64
+	progress           func(read, total int64)
+	warnings           func(Warning)
+	lineFilter         func(Line) (Line, bool)
+	ctx                context.Context
+	ctxIsSet           bool
+	expandNotes        bool
+	endNotes           bool
+	combineStrong      bool
+	preserveWhitespace bool
+	unknownElements    UnknownElements
+	handlers           map[string]ElementHandler
+	tagMapping         map[string]string
+	// sectionID or sectionIndex(if sectionID is empty) select the section of
+	// ParseSection
+	sectionID    string
+	sectionIndex int
+	sectionIsSet bool
+	// bodies are the names of bodies to return, nil means all bodies
+	bodies map[string]bool
 }
 
-type FOption func(option) option
+/*
+Option configures parsing. Options are created by the functions of this
+package(ParseBody, SkipSystemLines, MaxLines, etc.) and passed to ParseBook
+and other parsing functions. Without options the parser:
+  - reads only information about the book and stops at the first body
+  - emits empty lines, section and emphasis markers
+  - parses the first FB2 file of ZIP archive
+  - does not limit the number of parsed lines and bytes
+  - cannot be canceled
+
+Conflicting options(e.g. WithZipEntry together with WithZipEntryIndex) and
+invalid values make parsing functions return an error that wraps
+ErrInvalidOption
+*/
+type Option func(option) option
+
+/*
+FOption is the old name of Option.
+
+Deprecated: use Option instead
+*/
+type FOption = Option
+
+// newOption applies all functional options to the default option set and validates the result
+func newOption(opts []Option) (option, error) {
+	opt := option{}
+
+	for _, fun := range opts {
+		opt = fun(opt)
+	}
+
+	if err := opt.validate(); err != nil {
+		return opt, err
+	}
+	opt.handlers = opt.mappingHandlers()
+
+	return opt, nil
+}
 
-var Option option
+// validate checks that options values are valid and do not conflict
+func (o option) validate() error {
+	switch {
+	case o.zipEntry != "" && o.zipEntryIndexIsSet:
+		return fmt.Errorf("%w: WithZipEntry and WithZipEntryIndex cannot be used together", ErrInvalidOption)
+	case o.sectionIsSet && o.sectionID == "" && o.sectionIndex < 0:
+		return fmt.Errorf("%w: empty section id or negative section index", ErrInvalidOption)
+	case o.zipEntryIndex < 0:
+		return fmt.Errorf("%w: negative zip entry index %d", ErrInvalidOption, o.zipEntryIndex)
+	case o.size < 0:
+		return fmt.Errorf("%w: negative size hint %d", ErrInvalidOption, o.size)
+	case o.maxLines < 0:
+		return fmt.Errorf("%w: negative line limit %d", ErrInvalidOption, o.maxLines)
+	case o.maxBytes < 0:
+		return fmt.Errorf("%w: negative byte limit %d", ErrInvalidOption, o.maxBytes)
+	case o.expandNotes && o.endNotes:
+		return fmt.Errorf("%w: ExpandNotes and EndNotes cannot be used together", ErrInvalidOption)
+	case o.bodies != nil && len(o.bodies) == 0:
+		return fmt.Errorf("%w: no bodies selected", ErrInvalidOption)
+	case o.bodies[""]:
+		return fmt.Errorf("%w: empty body name", ErrInvalidOption)
+	case o.unknownElements < UnknownDrop || o.unknownElements > UnknownMarker:
+		return fmt.Errorf("%w: unknown elements mode %d", ErrInvalidOption, o.unknownElements)
+	case o.ctxIsSet && o.ctx == nil:
+		return fmt.Errorf("%w: nil context", ErrInvalidOption)
+	default:
+		return validateMapping(o.tagMapping)
+	}
+}
 
-func ParseBody() FOption {
+// ParseBody makes the parser read the book text, not only information about the book
+func ParseBody() Option {
 	return func(o option) option {
 		o.parseBody = true
 		return o
 	}
 }
 
-func SkipSystemLines() FOption {
+// SkipSystemLines makes the parser skip empty lines, section, poem, stanza, and emphasis markers
+func SkipSystemLines() Option {
 	return func(o option) option {
 		o.skipSystemLines = true
 		return o
 	}
 }
+
+/*
+SizeHint sets the size of the stream passed to ParseBookFromReader. It
+allows to read ZIP archive without loading it into memory if the stream
+implements io.ReaderAt. ParseBook sets the hint automatically
+*/
+func SizeHint(size int64) Option {
+	return func(o option) option {
+		o.size = size
+		return o
+	}
+}
+
+/*
+WithContext makes parsing cancellable. The context is checked before reading
+every XML token, and if it is canceled or its deadline is exceeded the parser
+stops and returns the book information and lines parsed so far together with
+ctx.Err()
+*/
+func WithContext(ctx context.Context) Option {
+	return func(o option) option {
+		o.ctx = ctx
+		o.ctxIsSet = true
+		return o
+	}
+}
+
+/*
+WithZipEntry selects FB2 file to parse in ZIP archive by its name(full path
+inside the archive). By default the first FB2 file in the archive is parsed
+*/
+func WithZipEntry(name string) Option {
+	return func(o option) option {
+		o.zipEntry = name
+		return o
+	}
+}
+
+/*
+WithZipEntryIndex selects FB2 file to parse in ZIP archive by its index among
+all FB2 files of the archive, see ListZipEntries. It cannot be used together
+with WithZipEntry
+*/
+func WithZipEntryIndex(i int) Option {
+	return func(o option) option {
+		o.zipEntryIndex = i
+		o.zipEntryIndexIsSet = true
+		return o
+	}
+}
+
+/*
+MaxLines stops parsing after n lines are parsed. It is useful for book
+previews and search indexing that need only the beginning of the text. Zero
+n means no limit. The note lines added by ExpandNotes or EndNotes count too,
+but only the notes parsed before the limit is reached are added
+*/
+func MaxLines(n int) Option {
+	return func(o option) option {
+		o.maxLines = n
+		return o
+	}
+}
+
+/*
+MaxBytes stops parsing after n bytes of FB2 XML are read. For archives the
+limit is applied to the unpacked XML. It protects from huge or pathological
+files. Zero n means no limit
+*/
+func MaxBytes(n int64) Option {
+	return func(o option) option {
+		o.maxBytes = n
+		return o
+	}
+}
+
+/*
+WithProgress sets a callback that reports parsing progress, e.g. to show a
+progress bar for a large book. read is the number of processed bytes and
+total is the size of the book. For raw FB2 and GZIP archives the bytes are
+counted in the source stream, so total is the stream size from SizeHint and
+it is zero if the size is unknown. For ZIP archives the bytes are counted in
+the unpacked FB2 file. The callback is called every 64KB and once when the
+parsing is finished
+*/
+func WithProgress(fn func(read, total int64)) Option {
+	return func(o option) option {
+		o.progress = fn
+		return o
+	}
+}
+
+/*
+WithWarnings sets a callback that receives non-fatal defects of the book:
+unknown elements, authors without names, duplicate sequences, etc. The
+defects do not stop parsing
+*/
+func WithWarnings(fn func(Warning)) Option {
+	return func(o option) option {
+		o.warnings = fn
+		return o
+	}
+}
+
+/*
+WithLineFilter sets a function that is called for every parsed line before
+it is added to the result. The function can rewrite the line(e.g. normalize
+dashes) or drop it by returning false(e.g. strip publisher ads). Dropped
+lines are not counted by MaxLines. The filter does not affect Visitor
+callbacks
+*/
+func WithLineFilter(fn func(Line) (Line, bool)) Option {
+	return func(o option) option {
+		o.lineFilter = fn
+		return o
+	}
+}
+
+/*
+ExpandNotes makes the parser insert the text of footnotes right after the
+paragraph that references them, for readers that cannot show footnotes in
+popups. The notes body is not returned, because the notes are already in
+the text. The notes are stored after the book text in FB2, so the lines are
+returned only when the whole book is parsed, even by Scanner
+*/
+func ExpandNotes() Option {
+	return func(o option) option {
+		o.expandNotes = true
+		return o
+	}
+}
+
+/*
+EndNotes makes the parser return the footnotes as a generated "Notes"
+section at the end of the text instead of the notes body. The notes
+referenced in the text go first in the order of the references, every note
+starts with its title in brackets, e.g. "[1] ", the same as the reference
+text in most books. It cannot be used together with ExpandNotes
+*/
+func EndNotes() Option {
+	return func(o option) option {
+		o.endNotes = true
+		return o
+	}
+}
+
+/*
+WithBodies selects the bodies whose lines are returned by name: MainBody for
+the main text, "notes", "comments", etc. AllBodies selects every body, it is
+the default. The information about not selected bodies(BookInfo.Notes and
+BookInfo.Bodies) is still collected
+*/
+func WithBodies(names ...string) Option {
+	return func(o option) option {
+		o.bodies = make(map[string]bool, len(names))
+		for _, name := range names {
+			o.bodies[name] = true
+		}
+		return o
+	}
+}
+
+/*
+CombineStrong makes the parser mark strong text the same way as emphasized
+text: StyleEmphasis spans and {{emon}}/{{emoff}} markers, as the previous
+versions did. By default strong text has StyleStrong spans and
+{{strongon}}/{{strongoff}} markers
+*/
+func CombineStrong() Option {
+	return func(o option) option {
+		o.combineStrong = true
+		return o
+	}
+}
+
+/*
+PreserveWhitespace makes the parser keep the whitespace of the book text as
+is: spaces are not squeezed and line breaks are not replaced with spaces, so
+ASCII art and indented verses survive. Only "\r\n" line breaks are converted
+to "\n". By default the whitespace is collapsed to single spaces
+*/
+func PreserveWhitespace() Option {
+	return func(o option) option {
+		o.preserveWhitespace = true
+		return o
+	}
+}
+
+/*
+WithUnknownElements sets what the parser does with the elements of the book
+text that are not a part of FB2 schema, e.g. vendor-specific tags: UnknownDrop
+(default), UnknownText, or UnknownMarker. The parser warns about unknown
+elements in any mode, see WithWarnings
+*/
+func WithUnknownElements(mode UnknownElements) Option {
+	return func(o option) option {
+		o.unknownElements = mode
+		return o
+	}
+}
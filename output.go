@@ -0,0 +1,72 @@
+package fb2text
+
+import "encoding/xml"
+
+/*
+OutputInfo is the information from <output> element about distribution of
+the book: whether it is free or paid and what parts of the book can be
+included into documents created from it. Values are as written in the book,
+e.g. Mode is "free" or "paid", IncludeAll is "require", "allow", or "deny"
+*/
+type OutputInfo struct {
+	Mode            string                `json:"mode"`
+	IncludeAll      string                `json:"includeAll"`
+	Price           string                `json:"price"`
+	Currency        string                `json:"currency"`
+	Parts           []OutputPart          `json:"parts"`
+	DocumentClasses []OutputDocumentClass `json:"documentClasses"`
+}
+
+// OutputPart is a part of the book with the rule of its inclusion
+type OutputPart struct {
+	Href    string `json:"href"`
+	Include string `json:"include"`
+}
+
+/*
+OutputDocumentClass is a class of documents that can be created from the
+book with its own price and parts
+*/
+type OutputDocumentClass struct {
+	Name   string       `json:"name"`
+	Create string       `json:"create"`
+	Price  string       `json:"price"`
+	Parts  []OutputPart `json:"parts"`
+}
+
+// outputStart handles the start of <output> element or an element inside it
+func (p *parser) outputStart(se xml.StartElement) {
+	binfo := &p.info
+	switch se.Name.Local {
+	case "output":
+		binfo.Outputs = append(binfo.Outputs, OutputInfo{
+			Mode:       attrValue(se, "mode"),
+			IncludeAll: attrValue(se, "include-all"),
+			Price:      attrValue(se, "price"),
+			Currency:   attrValue(se, "currency"),
+		})
+		return
+	}
+
+	n := len(binfo.Outputs)
+	if n == 0 {
+		return
+	}
+	out := &binfo.Outputs[n-1]
+	switch se.Name.Local {
+	case "output-document-class":
+		out.DocumentClasses = append(out.DocumentClasses, OutputDocumentClass{
+			Name:   attrValue(se, "name"),
+			Create: attrValue(se, "create"),
+			Price:  attrValue(se, "price"),
+		})
+	case "part":
+		part := OutputPart{Href: hrefValue(se), Include: attrValue(se, "include")}
+		if p.tags[len(p.tags)-1] == "output-document-class" && len(out.DocumentClasses) > 0 {
+			class := &out.DocumentClasses[len(out.DocumentClasses)-1]
+			class.Parts = append(class.Parts, part)
+		} else {
+			out.Parts = append(out.Parts, part)
+		}
+	}
+}
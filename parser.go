@@ -0,0 +1,802 @@
+package fb2text
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+/*
+parser keeps the state of FB2 parsing between XML tokens, so a book can be
+parsed either at once or line by line
+*/
+type parser struct {
+	opt     option
+	visitor *Visitor
+	// coverOnly is true if only the cover image is needed, the book text and
+	// other binaries are skipped
+	coverOnly bool
+	// binaries collects all decoded binaries if it is not nil, the book
+	// text is skipped
+	binaries map[string]Binary
+	book     *bookReader
+	decoder  *xml.Decoder
+	info     BookInfo
+	tags     []string
+	// langs contains the effective xml:lang of every element in tags
+	langs []string
+
+	// the line being parsed: its kind, text, styled parts, and indexes of
+	// spans that are not closed yet
+	currKind  Kind
+	currLine  string
+	currSpans []Span
+	openSpans []int
+	// currID is the id attribute of the paragraph of the current line
+	currID string
+
+	// table is the table being parsed, cell is its current cell
+	table *Table
+	cell  TableCell
+
+	// binary accumulates the content of <binary> element if visitor needs it
+	// or it is the cover
+	binary      *bytes.Buffer
+	binaryID    string
+	binaryCType string
+
+	// lines contains parsed lines that have not been taken by the caller yet,
+	// count is the number of lines parsed from the beginning of the book
+	lines []Line
+	count int
+	// held are the lines kept until the notes are parsed, see ExpandNotes
+	held Lines
+	// reported is the progress passed to the last progress callback call
+	reported int64
+	// author is the author in description being parsed
+	author Author
+	// genreMatch is the match attribute of the current genre
+	genreMatch int
+	// dateValue is the value attribute of the current date
+	dateValue string
+	// body is the name of the body being parsed
+	body string
+	// note is the footnote being collected from the notes body
+	note *Note
+	// noteIDs are the ids of the notes in the order of the notes body,
+	// noteRefs are the ids of the notes referenced in the text
+	noteIDs  []string
+	noteRefs []string
+	// anchorIDs are the ids of the elements waiting for their first line
+	anchorIDs []string
+	// sectionDepth is the number of open elements up to the section selected
+	// by ParseSection, it is zero outside the section. sections counts the
+	// top-level sections of the main body
+	sectionDepth int
+	sections     int
+	// customInfoType is the info-type attribute of the current custom-info
+	customInfoType string
+	// sequences contains names of sequences in title-info
+	sequences map[string]bool
+	// sequenceStack contains the sequences being parsed in description
+	sequenceStack []*Sequence
+	// root is true if the root element has been read
+	root bool
+	// charset is the encoding that charsetReader failed to decode
+	charset string
+	done    bool
+	err     error
+}
+
+func newParser(book *bookReader, opt option) *parser {
+	p := &parser{
+		opt:  opt,
+		tags: make([]string, 0, 10),
+	}
+	p.reset(book)
+
+	return p
+}
+
+/*
+reset prepares the parser to parse a new book. The options, the visitor,
+and the allocated buffers are kept, all other state is cleared. If book is
+nil the parser is reset to the finished state
+*/
+func (p *parser) reset(book *bookReader) {
+	var decoder *xml.Decoder
+	if book != nil {
+		decoder = xml.NewDecoder(book)
+		decoder.CharsetReader = p.charsetReader
+		// old converters use HTML entities like &nbsp; or &mdash;
+		decoder.Entity = xml.HTMLEntity
+	}
+
+	*p = parser{
+		opt:       p.opt,
+		visitor:   p.visitor,
+		coverOnly: p.coverOnly,
+		book:      book,
+		done:      book == nil,
+		decoder:   decoder,
+		tags:      p.tags[:0],
+		langs:     p.langs[:0],
+		openSpans: p.openSpans[:0],
+		lines:     p.lines[:0],
+	}
+}
+
+// finish stops parsing. err is nil if the parsing is finished successfully
+func (p *parser) finish(err error) {
+	if !p.done {
+		p.reportProgress(true)
+		p.releaseNotes()
+		p.resolveIDs()
+		if err == nil && p.opt.sectionIsSet && p.sectionDepth == 0 {
+			err = p.sectionError()
+		}
+	}
+	p.done = true
+	p.err = err
+}
+
+/*
+charsetReader converts the book text to UTF-8. It remembers the encoding it
+failed to convert, because the decoder does not keep the original error.
+UTF-16 books are converted when they are opened, so their text is kept
+*/
+func (p *parser) charsetReader(label string, input io.Reader) (io.Reader, error) {
+	if p.book.utf16 {
+		return input, nil
+	}
+
+	r, err := charset.NewReaderLabel(label, input)
+	if err != nil {
+		p.charset = label
+	}
+
+	return r, err
+}
+
+/*
+classify wraps the decoder error with the corresponding package error. The
+syntax errors have the byte offset of the error added, the decoder reports
+only the line
+*/
+func (p *parser) classify(err error) error {
+	var syntaxErr *xml.SyntaxError
+	switch {
+	case p.charset != "":
+		return fmt.Errorf("%w %q: %w", ErrUnsupportedEncoding, p.charset, err)
+	case errors.As(err, &syntaxErr) && !p.root:
+		return fmt.Errorf("%w: %w", ErrNotFB2, err)
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("%w: %w (offset %d)", ErrMalformedXML, err, p.decoder.InputOffset())
+	default:
+		return err
+	}
+}
+
+// checkSequence warns if the sequence name is empty or repeated
+func (p *parser) checkSequence(name string) {
+	if name == "" {
+		p.warn("sequence without name")
+		return
+	}
+
+	if p.sequences == nil {
+		p.sequences = make(map[string]bool)
+	}
+	if p.sequences[name] {
+		p.warn("duplicate sequence %q", name)
+	}
+	p.sequences[name] = true
+}
+
+// progressStep is the minimal number of bytes between progress reports
+const progressStep = 64 << 10
+
+/*
+reportProgress calls the progress callback. Intermediate progress is
+reported not more often than every progressStep bytes, the final one is
+always reported
+*/
+func (p *parser) reportProgress(final bool) {
+	if p.opt.progress == nil || p.book == nil {
+		return
+	}
+
+	read, total := p.book.progress(p.decoder.InputOffset())
+	if !final && read-p.reported < progressStep {
+		return
+	}
+	p.reported = read
+	p.opt.progress(read, total)
+}
+
+/*
+step reads and processes the next XML token. It returns false when parsing
+is finished. The reason why the parser stops is in p.err, it is nil if the
+end of the book is reached
+*/
+func (p *parser) step() bool {
+	if p.done {
+		return false
+	}
+
+	if p.opt.ctx != nil {
+		if err := p.opt.ctx.Err(); err != nil {
+			p.finish(err)
+			return false
+		}
+	}
+
+	if p.opt.maxBytes > 0 && p.decoder.InputOffset() >= p.opt.maxBytes {
+		p.finish(nil)
+		return false
+	}
+
+	t, err := p.decoder.Token()
+	if err == io.EOF && !p.root {
+		p.finish(fmt.Errorf("%w: no root element", ErrNotFB2))
+		return false
+	}
+	if err == io.EOF {
+		p.finish(nil)
+		return false
+	}
+	if err != nil {
+		p.finish(p.classify(err))
+		return false
+	}
+
+	// Inspect the type of the token just read.
+	switch se := t.(type) {
+	case xml.StartElement:
+		p.startElement(se)
+	case xml.EndElement:
+		p.endElement(se)
+	case xml.CharData:
+		p.charData(se)
+	case xml.Directive:
+		p.directive(se)
+	}
+	p.reportProgress(false)
+
+	return !p.done
+}
+
+func (p *parser) startElement(se xml.StartElement) {
+	if !p.root {
+		p.root = true
+		if se.Name.Local != "FictionBook" {
+			p.finish(fmt.Errorf("%w: root element is <%s>", ErrNotFB2, se.Name.Local))
+			return
+		}
+	}
+
+	p.detectVersion(se.Name.Local, se.Name.Space)
+	lang := p.lang()
+	if l := xmlLang(se); l != "" {
+		lang = l
+	}
+
+	if p.coverOnly && p.skipForCover(se) {
+		return
+	}
+	if p.binaries != nil && p.skipForBinaries(se) {
+		return
+	}
+	if p.opt.sectionIsSet && p.skipForSection(se) {
+		return
+	}
+
+	opt := p.opt
+	if !opt.parseBody && se.Name.Local == "body" {
+		p.finish(nil)
+		return
+	}
+
+	if lang != p.lang() {
+		p.visitLanguage(lang)
+	}
+
+	// ids are indexed before the handlers, so links to handled elements work
+	if isInBookContent(p.tags) {
+		p.indexID(se.Name.Local, attrValue(se, "id"), p.body)
+		p.anchorStart(attrValue(se, "id"))
+	} else if se.Name.Local == "binary" && len(p.tags) == 1 {
+		p.indexID(se.Name.Local, attrValue(se, "id"), "")
+	}
+
+	if p.handleStart(se) {
+		p.tags = append(p.tags, se.Name.Local)
+		p.langs = append(p.langs, lang)
+		return
+	}
+
+	if _, ok := p.opt.handlers[se.Name.Local]; !ok && !knownElements[se.Name.Local] {
+		p.warn("unknown element <%s>", se.Name.Local)
+	}
+
+	if isInBookContent(p.tags) {
+		p.tableStart(se)
+	}
+
+	if se.Name.Local == "body" {
+		p.body = attrValue(se, "name")
+		p.visitBodyStart(se)
+	} else if isInBookContent(p.tags) && isBlock(se.Name.Local) {
+		p.visitBlockStart(se.Name.Local)
+	} else if isInBookInfo(p.tags) {
+		p.titleInfoStart(se)
+	} else if isInDescription(p.tags, "src-title-info") {
+		p.srcTitleInfoStart(se)
+	} else if isInDescription(p.tags, "document-info") {
+		p.documentInfoStart(se)
+	} else if isInDescription(p.tags, "publish-info") {
+		p.publishInfoStart(se)
+	} else if isInDescription(p.tags, "output") ||
+		se.Name.Local == "output" && len(p.tags) == 2 && p.tags[1] == "description" {
+		p.outputStart(se)
+	} else if se.Name.Local == "custom-info" {
+		p.customInfoType = attrValue(se, "info-type")
+	}
+
+	if p.opt.unknownElements != UnknownDrop && p.isUnknown(se.Name.Local) {
+		p.unknownStart(se)
+	} else if se.Name.Local == "empty-line" {
+		line := Line{Kind: KindEmpty, Lang: lang, Depth: p.depth()}
+		p.noteLine(line)
+		if !opt.skipSystemLines {
+			p.addLine(line)
+		}
+		p.visitEmptyLine()
+		p.resetLine(KindParagraph)
+	} else if se.Name.Local == "section" {
+		if p.body == notesBody && len(p.tags) == 2 {
+			p.noteStart(attrValue(se, "id"))
+		}
+		id := attrValue(se, "id")
+		if !opt.skipSystemLines {
+			p.addLine(Line{Kind: KindSection, Lang: lang, Depth: p.depth() + 1, ID: id})
+		}
+		if p.visitor != nil && p.visitor.OnSectionStart != nil {
+			p.visitor.OnSectionStart()
+		}
+		if id != "" && p.visitor != nil && p.visitor.OnSectionID != nil {
+			p.visitor.OnSectionID(id)
+		}
+		p.resetLine(KindParagraph)
+	} else if (se.Name.Local == "poem" || se.Name.Local == "stanza") && isInBookContent(p.tags) {
+		kind := KindPoem
+		if se.Name.Local == "stanza" {
+			kind = KindStanza
+		}
+		line := Line{Kind: kind, Lang: lang, Depth: p.depth()}
+		p.noteLine(line)
+		if !opt.skipSystemLines {
+			p.addLine(line)
+		}
+		p.resetLine(KindParagraph)
+	} else if se.Name.Local == "emphasis" || se.Name.Local == "strong" {
+		p.openSpan(p.emphasisStyle(se.Name.Local), "")
+	} else if se.Name.Local == "image" && isInBookContent(p.tags) {
+		p.imageStart(se)
+	} else if isInline(se.Name.Local) {
+		if isInBookContent(p.tags) && se.Name.Local == "a" {
+			p.openLink(se)
+		} else if isInBookContent(p.tags) && se.Name.Local == "style" {
+			p.openSpan(StyleNamed, attrValue(se, "name"))
+		} else if style, ok := inlineStyles[se.Name.Local]; ok && isInBookContent(p.tags) {
+			p.openSpan(style, "")
+		}
+	} else if se.Name.Local == "sequence" {
+		if isInBookInfo(p.tags) {
+			p.checkSequence(attrValue(se, "name"))
+		}
+	} else if se.Name.Local == "binary" && p.needBinary(attrValue(se, "id")) {
+		p.binary = new(bytes.Buffer)
+		p.binaryID = attrValue(se, "id")
+		p.binaryCType = attrValue(se, "content-type")
+	} else {
+		if se.Name.Local == "text-author" && isInEpigraph(p.tags) {
+			p.resetLine(KindEpigraphAuthor)
+		} else if se.Name.Local == "text-author" && isInside(p.tags, "cite") {
+			p.resetLine(KindCiteAuthor)
+		} else if se.Name.Local == "text-author" && isInBookContent(p.tags) && isInside(p.tags, "poem") {
+			p.resetLine(KindPoemAuthor)
+		} else if se.Name.Local == "p" {
+			if isInEpigraph(p.tags) {
+				p.resetLine(KindEpigraph)
+			} else if isInside(p.tags, "cite") {
+				p.resetLine(KindCite)
+			} else if isInside(p.tags, "annotation") && isInBookContent(p.tags) {
+				p.resetLine(KindAnnotation)
+			} else if isPoemTitle(p.tags) {
+				p.resetLine(KindPoemTitle)
+			} else if isStanzaTitle(p.tags) {
+				p.resetLine(KindStanzaTitle)
+			} else if isInside(p.tags, "title") {
+				p.resetLine(KindTitle)
+			} else {
+				p.resetLine(KindParagraph)
+			}
+		} else if se.Name.Local == "v" && isInBookContent(p.tags) {
+			p.resetLine(KindVerse)
+		} else if se.Name.Local == "subtitle" && isInBookContent(p.tags) && isInside(p.tags, "stanza") {
+			p.resetLine(KindStanzaSubtitle)
+		} else if se.Name.Local == "subtitle" && isInBookContent(p.tags) && isInside(p.tags, "poem") {
+			p.resetLine(KindPoemSubtitle)
+		} else if se.Name.Local == "subtitle" && isInBookContent(p.tags) {
+			p.resetLine(KindSubtitle)
+		} else if se.Name.Local == "date" && isInBookContent(p.tags) && isInside(p.tags, "poem") {
+			p.resetLine(KindPoemDate)
+		} else if se.Name.Local == "date" && isInBookContent(p.tags) {
+			p.resetLine(KindDate)
+		} else {
+			p.resetLine(KindParagraph)
+		}
+	}
+	switch se.Name.Local {
+	case "p", "v", "subtitle", "text-author":
+		p.currID = attrValue(se, "id")
+	}
+	p.tags = append(p.tags, se.Name.Local)
+	p.langs = append(p.langs, lang)
+}
+
+func (p *parser) endElement(se xml.EndElement) {
+	// the decoder checks that the tags are balanced
+	tags := p.tags[:len(p.tags)-1]
+	p.tags = tags
+
+	if p.handleEnd(se.Name.Local) {
+		// the element is handled by WithElementHandler
+	} else if isInBookInfo(tags) {
+		p.titleInfoEnd(se.Name.Local, tags)
+	} else if isInDescription(tags, "src-title-info") {
+		p.srcTitleInfoEnd(se.Name.Local, tags)
+	} else if isInDescription(tags, "document-info") {
+		p.documentInfoEnd(se.Name.Local, tags)
+	} else if isInDescription(tags, "publish-info") {
+		p.publishInfoEnd(se.Name.Local, tags)
+	} else if isInBookContent(tags) {
+		if p.opt.unknownElements != UnknownDrop && p.isUnknown(se.Name.Local) {
+			p.unknownEnd()
+		} else if se.Name.Local == "emphasis" || se.Name.Local == "strong" {
+			p.closeSpan(p.emphasisStyle(se.Name.Local))
+		} else if se.Name.Local == "a" {
+			p.closeLink()
+		} else if se.Name.Local == "style" {
+			p.closeSpan(StyleNamed)
+		} else if style, ok := inlineStyles[se.Name.Local]; ok {
+			p.closeSpan(style)
+		} else if isInline(se.Name.Local) || se.Name.Local == "image" {
+			// inline elements do not break the line
+		} else if se.Name.Local == "section" {
+			p.emitLine()
+			if p.visitor != nil && p.visitor.OnSectionEnd != nil {
+				p.visitor.OnSectionEnd()
+			}
+			if p.note != nil && len(tags) == 2 {
+				p.noteEnd()
+			}
+		} else {
+			p.tableEnd(se.Name.Local)
+			p.emitLine()
+			if isBlock(se.Name.Local) {
+				p.visitBlockEnd(se.Name.Local)
+			}
+		}
+	} else if se.Name.Local == "custom-info" && len(tags) == 2 {
+		p.customInfoEnd()
+	} else if se.Name.Local == "body" && len(tags) == 1 {
+		p.resetLine(KindParagraph)
+		if p.visitor != nil && p.visitor.OnBodyEnd != nil {
+			p.visitor.OnBodyEnd()
+		}
+	} else if se.Name.Local == "binary" && p.binary != nil {
+		p.binaryEnd()
+		p.binary = nil
+	} else {
+		p.resetLine(KindParagraph)
+	}
+
+	// the language of the element is needed until its last line is emitted
+	if n := len(p.langs); n > 0 {
+		lang := p.langs[n-1]
+		p.langs = p.langs[:n-1]
+		if lang != p.lang() {
+			p.visitLanguage(p.lang())
+		}
+	}
+	p.sectionEnd()
+}
+
+// lang returns the effective language of the current element
+func (p *parser) lang() string {
+	if n := len(p.langs); n > 0 {
+		return p.langs[n-1]
+	}
+
+	return ""
+}
+
+// needBinary returns true if the content of the binary id should be decoded
+func (p *parser) needBinary(id string) bool {
+	if p.visitor != nil && p.visitor.OnBinary != nil || p.binaries != nil {
+		return true
+	}
+
+	return p.isCover(id)
+}
+
+// binaryEnd decodes the content of <binary> and passes it to its consumers
+func (p *parser) binaryEnd() {
+	content := strings.Join(strings.Fields(p.binary.String()), "")
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		p.warn("invalid content of binary %q: %v", p.binaryID, err)
+		return
+	}
+
+	if p.isCover(p.binaryID) {
+		p.addCover(Cover{ID: p.binaryID, ContentType: p.binaryCType, Data: data})
+		if p.coverOnly && len(p.info.Covers) == len(p.info.CoverIDs) {
+			p.finish(nil)
+			return
+		}
+	}
+	p.addBinary(data)
+	p.visitBinary(data)
+}
+
+/*
+charData adds the text to the current line. CDATA sections are character data
+too, so their text follows the same whitespace rules as the rest of the text
+*/
+func (p *parser) charData(se xml.CharData) {
+	if p.binary != nil {
+		p.binary.Write(se)
+		return
+	}
+
+	ss := string(se)
+	if p.handleCharData(ss) {
+		return
+	}
+	if p.inCode() || p.keepWhitespace() {
+		p.currLine += strings.ReplaceAll(ss, "\r\n", "\n")
+		return
+	}
+
+	// whitespace between the parts of a text is a space, the rest of the
+	// whitespace is the formatting of the file
+	n := len(p.tags)
+	if strings.TrimFunc(ss, isBreakingSpace) == "" && (n == 0 || !isText(p.tags[n-1])) {
+		return
+	}
+	p.currLine += collapseSpace(p.currLine, ss)
+}
+
+// resetLine discards the current line and starts a new one of the given kind
+func (p *parser) resetLine(kind Kind) {
+	p.currKind = kind
+	p.currLine = ""
+	p.currSpans = nil
+	p.openSpans = p.openSpans[:0]
+	p.currID = ""
+}
+
+// emphasisStyle returns the span style of <emphasis> or <strong>
+func (p *parser) emphasisStyle(name string) SpanStyle {
+	if name == "strong" && !p.opt.combineStrong {
+		return StyleStrong
+	}
+
+	return StyleEmphasis
+}
+
+// openSpan starts styled part of the current line
+func (p *parser) openSpan(style SpanStyle, target string) {
+	p.openSpans = append(p.openSpans, len(p.currSpans))
+	p.currSpans = append(p.currSpans, Span{Start: len(p.currLine), End: -1, Style: style, Target: target})
+}
+
+/*
+openLink starts a link of the current line. Links of type "note" to the
+elements of the book are note references, other links to "#id" are anchors,
+all the rest are external links
+*/
+func (p *parser) openLink(se xml.StartElement) {
+	href := hrefValue(se)
+	id, internal := strings.CutPrefix(href, "#")
+	switch {
+	case internal && attrValue(se, "type") == "note":
+		p.openSpan(StyleNote, id)
+		if p.body != notesBody {
+			p.noteRefs = append(p.noteRefs, id)
+		}
+	case internal:
+		p.openSpan(StyleAnchor, id)
+	default:
+		p.openSpan(StyleLink, href)
+	}
+}
+
+// closeLink ends the innermost link of the current line
+func (p *parser) closeLink() {
+	n := len(p.openSpans)
+	if n == 0 {
+		return
+	}
+
+	switch style := p.currSpans[p.openSpans[n-1]].Style; style {
+	case StyleNote, StyleLink, StyleAnchor:
+		p.closeSpan(style)
+	}
+}
+
+/*
+closeSpan ends the innermost styled part of the current line if it has the
+given style. Empty styled parts are removed, except note references and
+images, which are kept because they mark the position
+*/
+func (p *parser) closeSpan(style SpanStyle) {
+	n := len(p.openSpans)
+	if n == 0 || p.currSpans[p.openSpans[n-1]].Style != style {
+		return
+	}
+
+	idx := p.openSpans[n-1]
+	p.openSpans = p.openSpans[:n-1]
+	span := &p.currSpans[idx]
+	span.End = len(p.currLine)
+	if span.End == span.Start && style != StyleNote && style != StyleImage {
+		// nothing is styled, the spans opened after this one are closed
+		// already, so they are shifted
+		p.currSpans = append(p.currSpans[:idx], p.currSpans[idx+1:]...)
+		return
+	}
+
+	if (style == StyleEmphasis || style == StyleStrong) && isInBookContent(p.tags) {
+		p.visitEmphasis(span.Start)
+	}
+}
+
+// line returns the current line. Spans still open cover the rest of the line
+func (p *parser) line() Line {
+	for _, idx := range p.openSpans {
+		p.currSpans[idx].End = len(p.currLine)
+	}
+
+	return Line{
+		Kind:  p.currKind,
+		Text:  p.currLine,
+		Spans: p.currSpans,
+		Lang:  p.lang(),
+		Depth: p.depth(),
+		ID:    p.currID,
+	}
+}
+
+// depth returns the number of sections the current element is nested in
+func (p *parser) depth() int {
+	depth := 0
+	for _, tag := range p.tags {
+		if tag == "section" {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+/*
+emitLine adds the current line to the parsed lines and starts a new line.
+Empty paragraphs are skipped
+*/
+func (p *parser) emitLine() {
+	if p.currKind != KindParagraph || p.currLine != "" {
+		line := codeLine(p.line())
+		p.visitLine(line)
+		p.noteLine(line)
+		// note references are needed to expand the notes, the spans of
+		// held lines are removed when they are released
+		if p.opt.skipSystemLines && !p.opt.expandNotes {
+			line.Spans = nil
+		}
+		p.addLine(line)
+	}
+	p.resetLine(KindParagraph)
+}
+
+/*
+addLine passes the line through the line filter and adds it to the parsed
+lines. When the limit of lines is reached the parsing is stopped
+*/
+func (p *parser) addLine(line Line) {
+	if p.opt.maxLines > 0 && p.count >= p.opt.maxLines {
+		return
+	}
+	if p.opt.sectionIsSet && p.sectionDepth == 0 {
+		p.anchorIDs = p.anchorIDs[:0]
+		return
+	}
+	line.Body = p.body
+
+	if p.opt.lineFilter != nil {
+		var keep bool
+		if line, keep = p.opt.lineFilter(line); !keep {
+			return
+		}
+	}
+
+	p.bodyLine(line)
+	if !p.bodySelected() {
+		p.anchorIDs = p.anchorIDs[:0]
+		return
+	}
+	if (p.opt.expandNotes || p.opt.endNotes) && p.body == notesBody {
+		// the notes are added to the text when the book is parsed
+		p.anchorIDs = p.anchorIDs[:0]
+		return
+	}
+
+	p.addAnchors(p.count)
+	if p.opt.expandNotes {
+		p.held = append(p.held, line)
+	} else {
+		p.lines = append(p.lines, line)
+	}
+	p.count++
+	if p.opt.maxLines > 0 && p.count >= p.opt.maxLines {
+		p.finish(nil)
+	}
+}
+
+// inCode returns true if the text being parsed is inside <code> of the book text
+func (p *parser) inCode() bool {
+	for i := len(p.tags) - 1; i >= 0 && isText(p.tags[i]); i-- {
+		if p.tags[i] == "code" {
+			return isInBookContent(p.tags)
+		}
+	}
+
+	return false
+}
+
+/*
+keepWhitespace returns true if the text of the current element is kept as is
+because of option PreserveWhitespace
+*/
+func (p *parser) keepWhitespace() bool {
+	n := len(p.tags)
+	return p.opt.preserveWhitespace && n > 0 && isText(p.tags[n-1]) && isInBookContent(p.tags)
+}
+
+/*
+codeLine converts the paragraph that consists of code only to KindCode line.
+Other lines are returned as is
+*/
+func codeLine(line Line) Line {
+	if line.Kind != KindParagraph || len(line.Spans) != 1 {
+		return line
+	}
+
+	span := line.Spans[0]
+	if span.Style != StyleCode || span.Start != 0 || span.End != len(line.Text) {
+		return line
+	}
+	line.Kind = KindCode
+	line.Spans = nil
+
+	return line
+}
@@ -0,0 +1,124 @@
+package fb2text
+
+import (
+	"strings"
+	"testing"
+)
+
+// cdataBook has CDATA sections in a paragraph, in code, and in annotations
+const cdataBook = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>CDATA</book-title>
+<annotation><p><![CDATA[An  <annotated>
+ book]]></p></annotation>
+</title-info></description>
+<body><section>
+<annotation><p>Section <![CDATA[a  &
+ b]]></p></annotation>
+<p>Text <![CDATA[a  < b &&
+  c]]> end</p>
+<p><code><![CDATA[if a < b {
+    x()
+}]]></code></p>
+</section></body>
+</FictionBook>`
+
+// titleBook returns a minimal FB2 book with the title and one paragraph
+func titleBook(title string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>` + title + `</book-title></title-info></description>
+<body><section id="s1"><p>Text</p></section></body>
+</FictionBook>`
+}
+
+func TestCDATA(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		annotation string
+		lines      Lines
+	}{
+		{
+			name:       "collapsed whitespace",
+			opts:       []Option{ParseBody()},
+			annotation: "An <annotated> book",
+			lines: Lines{
+				{Kind: KindAnnotation, Text: "Section a & b"},
+				{Kind: KindParagraph, Text: "Text a < b && c end"},
+				{Kind: KindCode, Text: "if a < b {\n    x()\n}"},
+			},
+		},
+		{
+			name: "preserved whitespace",
+			opts: []Option{ParseBody(), PreserveWhitespace()},
+			// the option keeps the whitespace of the book content only
+			annotation: "An <annotated> book",
+			lines: Lines{
+				{Kind: KindAnnotation, Text: "Section a  &\n b"},
+				{Kind: KindParagraph, Text: "Text a  < b &&\n  c end"},
+				{Kind: KindCode, Text: "if a < b {\n    x()\n}"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, lines, err := ParseBookLinesFromReader(strings.NewReader(cdataBook), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Annotation != tt.annotation {
+				t.Errorf("annotation = %q, want %q", info.Annotation, tt.annotation)
+			}
+
+			var got Lines
+			for _, line := range lines {
+				if line.Text != "" {
+					got = append(got, line)
+				}
+			}
+			if len(got) != len(tt.lines) {
+				t.Fatalf("got %d text lines %q, want %d", len(got), got.Strings(), len(tt.lines))
+			}
+			for i, want := range tt.lines {
+				if got[i].Kind != want.Kind || got[i].Text != want.Text {
+					t.Errorf("line %d = %v %q, want %v %q", i, got[i].Kind, got[i].Text, want.Kind, want.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		book string
+		want string
+	}{
+		{
+			name: "not XML",
+			book: "plain text",
+			want: "no root element",
+		},
+		{
+			name: "wrong root",
+			book: `<?xml version="1.0"?><html><body/></html>`,
+			want: "root element is <html>",
+		},
+		{
+			name: "malformed XML",
+			book: strings.Replace(titleBook("T"), "</p>", "</emphasis>", 1),
+			want: "element <p> closed by </emphasis> (offset ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseBookFromReader(strings.NewReader(tt.book), ParseBody())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package fb2text
+
+import "strings"
+
+/*
+Quality is the report about completeness of the book information. Missing
+contains the names of fields that are not set: "title", "authors",
+"language", "genre", "annotation", "cover", and "sequence". Score is the
+percentage of fields that are set
+*/
+type Quality struct {
+	Missing []string
+	Score   int
+}
+
+// Complete returns true if all checked fields are set
+func (q Quality) Complete() bool {
+	return len(q.Missing) == 0
+}
+
+/*
+Quality checks what important information is missing in the book
+description. It allows to find badly tagged books in a library. The cover
+is present if the coverpage references an image, so the body does not need
+to be parsed
+*/
+func (b BookInfo) Quality() Quality {
+	checks := []struct {
+		name string
+		ok   bool
+	}{
+		{"title", strings.TrimSpace(b.Title) != ""},
+		{"authors", len(b.Authors) > 0},
+		{"language", strings.TrimSpace(b.Language) != ""},
+		{"genre", len(b.Genres) > 0},
+		{"annotation", strings.TrimSpace(b.Annotation) != ""},
+		{"cover", len(b.CoverIDs) > 0},
+		{"sequence", len(b.Sequences) > 0 || len(b.PublisherSequences) > 0},
+	}
+
+	q := Quality{Missing: make([]string, 0)}
+	for _, check := range checks {
+		if !check.ok {
+			q.Missing = append(q.Missing, check.name)
+		}
+	}
+	q.Score = (len(checks) - len(q.Missing)) * 100 / len(checks)
+
+	return q
+}
@@ -0,0 +1,94 @@
+package fb2text
+
+import (
+	"bufio"
+	"io"
+)
+
+/*
+Parser is a reusable book parser for applications that parse a lot of books.
+Unlike ParseBook, that allocates new buffers for every book, Parser keeps
+its internal buffers between books. A Parser is not safe for concurrent use,
+but it can be kept in sync.Pool:
+
+	var pool = sync.Pool{
+		New: func() any { return fb2text.NewParser(fb2text.ParseBody()) },
+	}
+
+	ps := pool.Get().(*fb2text.Parser)
+	defer pool.Put(ps)
+	if err := ps.Reset(file); err != nil {
+		return err
+	}
+	info, lines, err := ps.Parse()
+*/
+type Parser struct {
+	p      *parser
+	br     *bufio.Reader
+	closer io.Closer
+	// optErr is the error of options validation, it is returned by Reset
+	optErr error
+}
+
+/*
+NewParser creates a Parser with the options that are used for every book.
+If the options are invalid every call to Reset returns the error
+*/
+func NewParser(opts ...Option) *Parser {
+	opt, err := newOption(opts)
+
+	return &Parser{
+		// nothing to parse until Reset is called
+		p:      newParser(nil, opt),
+		br:     bufio.NewReader(nil),
+		optErr: err,
+	}
+}
+
+/*
+Reset prepares the parser to parse a new book from r. The stream can be a raw
+FB2, ZIP or GZIP archive. The book parsed before is released, so the lines
+returned by the previous call to Parse must not be used after Reset
+*/
+func (ps *Parser) Reset(r io.Reader) error {
+	ps.close()
+	if ps.optErr != nil {
+		ps.p.reset(nil)
+		ps.p.finish(ps.optErr)
+		return ps.optErr
+	}
+
+	book, err := openBuffered(r, ps.br, ps.p.opt)
+	if err != nil {
+		ps.p.reset(nil)
+		ps.p.finish(err)
+		return err
+	}
+
+	ps.closer = book
+	ps.p.reset(book)
+
+	return nil
+}
+
+/*
+Parse parses the whole book set by Reset. It returns the same results as
+ParseBookLines. The returned lines use the parser buffer and they are valid
+until the next call to Reset
+*/
+func (ps *Parser) Parse() (BookInfo, Lines, error) {
+	p := ps.p
+	for p.step() {
+	}
+	ps.close()
+
+	return p.info, p.lines, p.err
+}
+
+// close releases the current book
+func (ps *Parser) close() {
+	if ps.closer != nil {
+		ps.closer.Close()
+		ps.closer = nil
+	}
+}
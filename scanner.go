@@ -0,0 +1,147 @@
+package fb2text
+
+import (
+	"io"
+	"os"
+)
+
+/*
+Scanner reads a book line by line. Unlike ParseBook it does not keep the
+whole book in memory and returns every line as soon as it is parsed, so an
+application can start displaying the text before the entire file is read.
+
+Typical usage:
+
+	sc, err := fb2text.OpenBook(fileName, fb2text.ParseBody())
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	for sc.Scan() {
+		fmt.Println(sc.Text())
+	}
+	return sc.Err()
+
+Lines are available both in the internal format returned by ParseBook and
+as typed Line values
+*/
+type Scanner struct {
+	p      *parser
+	closer []io.Closer
+	line   Line
+	// pos is the index of the next line in the parsed lines
+	pos int
+}
+
+/*
+NewScanner creates a Scanner that reads the book from r. The stream can be
+a raw FB2, ZIP or GZIP archive. It accepts the same options as ParseBook
+*/
+func NewScanner(r io.Reader, opts ...Option) (*Scanner, error) {
+	opt, err := newOption(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := openBook(r, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scanner{
+		p:      newParser(book, opt),
+		closer: []io.Closer{book},
+	}, nil
+}
+
+/*
+OpenBook opens the file fileName and creates a Scanner to read it. The
+caller must call Close when the scanner is no longer needed
+*/
+func OpenBook(fileName string, opts ...Option) (*Scanner, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	sc, err := NewScanner(file, opts...)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	sc.closer = append(sc.closer, file)
+
+	return sc, nil
+}
+
+/*
+Scan advances the scanner to the next line, which is available through the
+Text method. It returns false when the book is over or an error occurred.
+After Scan returns false, the Err method returns the error, if any
+*/
+func (s *Scanner) Scan() bool {
+	p := s.p
+	if s.pos == len(p.lines) {
+		// all parsed lines are taken, the buffer can be reused
+		p.lines = p.lines[:0]
+		s.pos = 0
+	}
+	for len(p.lines) == 0 && p.step() {
+	}
+
+	if s.pos == len(p.lines) {
+		s.line = Line{}
+		return false
+	}
+
+	s.line = p.lines[s.pos]
+	s.pos++
+
+	return true
+}
+
+/*
+Text returns the most recent line read by a call to Scan in the internal
+string format
+*/
+func (s *Scanner) Text() string {
+	return s.line.String()
+}
+
+// Line returns the most recent line read by a call to Scan
+func (s *Scanner) Line() Line {
+	return s.line
+}
+
+/*
+Err returns the first error encountered by the Scanner. The error is nil if
+the book is read to the end
+*/
+func (s *Scanner) Err() error {
+	return s.p.err
+}
+
+/*
+Info returns information about the book. Book information precedes the book
+text in FB2, so it is complete by the time the first line of the text is
+scanned
+*/
+func (s *Scanner) Info() BookInfo {
+	return s.p.info
+}
+
+// Close releases all resources used by the Scanner
+func (s *Scanner) Close() error {
+	var err error
+	for _, c := range s.closer {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,59 @@
+package fb2text
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	_, want, err := ParseBookLinesFromReader(strings.NewReader(notesBook), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := NewScanner(strings.NewReader(notesBook), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	var got Lines
+	for sc.Scan() {
+		if sc.Text() != sc.Line().String() {
+			t.Errorf("Text() = %q, want %q", sc.Text(), sc.Line().String())
+		}
+		got = append(got, sc.Line())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned lines =\n%q\nwant\n%q", got.Strings(), want.Strings())
+	}
+	if sc.Info().Title != "Notes" {
+		t.Errorf("title = %q, want %q", sc.Info().Title, "Notes")
+	}
+}
+
+func TestScannerError(t *testing.T) {
+	book := strings.Replace(notesBook, "</strong>", "</emphasis>", 1)
+	sc, err := NewScanner(strings.NewReader(book), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+
+	n := 0
+	for sc.Scan() {
+		n++
+	}
+	if !errors.Is(sc.Err(), ErrMalformedXML) {
+		t.Errorf("error = %v, want %v", sc.Err(), ErrMalformedXML)
+	}
+	// the lines before the broken paragraph are returned
+	if n != 6 {
+		t.Errorf("scanned %d lines, want 6", n)
+	}
+}
@@ -0,0 +1,99 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+)
+
+/*
+ParseSection parses only the section with the given id, e.g. a chapter to
+resume reading from. The elements before the section are skipped without
+building lines and parsing stops at the end of the section, so earlier
+chapters cost only XML tokenizing. The section can be in any body and can be
+nested. BookInfo has the book description, the information collected from
+the book text(Notes, Bodies, IDs, etc.) is incomplete. Returns ErrNoSection
+if the book has no such section
+*/
+func ParseSection(fileName, sectionID string, opts ...Option) (BookInfo, Lines, error) {
+	return parseSection(fileName, append(opts, func(o option) option {
+		o.sectionID = sectionID
+		o.sectionIndex = -1
+		o.sectionIsSet = true
+		return o
+	}))
+}
+
+/*
+ParseSectionIndex works the same way as ParseSection but selects the
+top-level section of the main body by its index starting from 0
+*/
+func ParseSectionIndex(fileName string, index int, opts ...Option) (BookInfo, Lines, error) {
+	return parseSection(fileName, append(opts, func(o option) option {
+		o.sectionID = ""
+		o.sectionIndex = index
+		o.sectionIsSet = true
+		return o
+	}))
+}
+
+func parseSection(fileName string, opts []Option) (BookInfo, Lines, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return BookInfo{}, make(Lines, 0), err
+	}
+	defer file.Close()
+
+	opts = append([]Option{ParseBody()}, opts...)
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return ParseBookLinesFromReader(file, opts...)
+}
+
+/*
+skipForSection skips the elements of the book text before the selected
+section. Sections are not skipped because they can contain the selected one
+*/
+func (p *parser) skipForSection(se xml.StartElement) bool {
+	if p.sectionDepth > 0 || !isInBookContent(p.tags) {
+		return false
+	}
+
+	if se.Name.Local != "section" {
+		if err := p.decoder.Skip(); err != nil {
+			p.finish(p.classify(err))
+		}
+		return true
+	}
+
+	selected := false
+	if p.opt.sectionID != "" {
+		selected = attrValue(se, "id") == p.opt.sectionID
+	} else if p.body == "" && len(p.tags) == 2 {
+		selected = p.sections == p.opt.sectionIndex
+		p.sections++
+	}
+	if selected {
+		p.sectionDepth = len(p.tags) + 1
+	}
+
+	return false
+}
+
+// sectionEnd stops parsing at the end of the selected section
+func (p *parser) sectionEnd() {
+	if p.sectionDepth > 0 && len(p.tags)+1 == p.sectionDepth {
+		p.finish(nil)
+	}
+}
+
+// sectionError returns the error of the section that is not found
+func (p *parser) sectionError() error {
+	if p.opt.sectionID != "" {
+		return fmt.Errorf("%w: id %q", ErrNoSection, p.opt.sectionID)
+	}
+
+	return fmt.Errorf("%w: index %d", ErrNoSection, p.opt.sectionIndex)
+}
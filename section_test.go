@@ -0,0 +1,64 @@
+package fb2text
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSection(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "notes.fb2")
+	if err := os.WriteFile(fileName, []byte(notesBook), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	byID := func(id string) func() (BookInfo, Lines, error) {
+		return func() (BookInfo, Lines, error) { return ParseSection(fileName, id) }
+	}
+	byIndex := func(index int) func() (BookInfo, Lines, error) {
+		return func() (BookInfo, Lines, error) { return ParseSectionIndex(fileName, index) }
+	}
+	chapter := []string{
+		"{{section#ch1}}",
+		"{{title}}One",
+		"{{epi}}Epi",
+		"{{epiauth}}Author",
+		"A {{emon}}big{{emoff}} word{{note:n1}}1{{noteoff}} here.",
+		"Code {{lb}}{x}} {{strongon}}bold{{strongoff}}",
+		"",
+		"{{section:2}}",
+		"Inner",
+	}
+
+	tests := []struct {
+		name  string
+		parse func() (BookInfo, Lines, error)
+		lines []string
+		err   error
+	}{
+		{name: "by id", parse: byID("ch1"), lines: chapter},
+		{name: "note by id", parse: byID("n1"), lines: []string{"{{section#n1}}", "{{title}}1", "Note text."}},
+		{name: "by index", parse: byIndex(0), lines: chapter},
+		{name: "no section", parse: byID("ch2"), err: ErrNoSection},
+		{name: "index out of range", parse: byIndex(1), err: ErrNoSection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, lines, err := tt.parse()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if info.Title != "Notes" {
+				t.Errorf("title = %q, want %q", info.Title, "Notes")
+			}
+			if tt.err != nil {
+				return
+			}
+			if got := lines.Strings(); !reflect.DeepEqual(got, tt.lines) {
+				t.Errorf("lines =\n%q\nwant\n%q", got, tt.lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package fb2text
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at
+const sniffLen = 512
+
+/*
+bookReader is a stream of FB2 XML. It knows how to measure the parsing
+progress: for raw and GZIP streams the progress is the number of bytes read
+from the source stream, for ZIP archives it is the offset in the unpacked
+FB2 file
+*/
+type bookReader struct {
+	io.ReadCloser
+	// size is the total size the progress is measured against, it is zero
+	// or negative if the size is unknown
+	size int64
+	// src counts bytes read from the source stream, it is nil for ZIP
+	src *countingReader
+	// utf16 is true if the stream is converted to UTF-8 from UTF-16, the
+	// encoding of the XML declaration is ignored then
+	utf16 bool
+}
+
+// progress returns the number of processed bytes and the total number of bytes
+func (b *bookReader) progress(offset int64) (int64, int64) {
+	if b.src != nil {
+		return b.src.n, b.size
+	}
+
+	return offset, b.size
+}
+
+// countingReader counts bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+/*
+openBook detects the format of the stream and returns a reader of the FB2
+XML. ZIP and GZIP archives are unpacked, any other data is returned as is.
+opt.size is the length of the stream, zero or negative value means the length
+is unknown
+*/
+func openBook(r io.Reader, opt option) (*bookReader, error) {
+	return openBuffered(r, bufio.NewReader(nil), opt)
+}
+
+// openBuffered works as openBook but reads r through the given buffered reader br
+func openBuffered(r io.Reader, br *bufio.Reader, opt option) (*bookReader, error) {
+	size := opt.size
+	if sz, ok := r.(interface{ Size() int64 }); ok && size <= 0 {
+		size = sz.Size()
+	}
+
+	src := &countingReader{r: r}
+	br.Reset(src)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch http.DetectContentType(head) {
+	case "application/x-gzip":
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &bookReader{ReadCloser: zr, size: size, src: src}, nil
+	case "application/zip":
+		return openZip(r, br, size, opt)
+	default:
+		if enc := utf16Encoding(head); enc != nil {
+			rc := io.NopCloser(transform.NewReader(br, enc.NewDecoder()))
+			return &bookReader{ReadCloser: rc, size: size, src: src, utf16: true}, nil
+		}
+		return &bookReader{ReadCloser: io.NopCloser(br), size: size, src: src}, nil
+	}
+}
+
+/*
+utf16Encoding returns the UTF-16 encoding of the stream that starts with
+head or nil if the stream is not UTF-16. The encoding is detected by the
+byte order mark or by the first "<" of the XML
+*/
+func utf16Encoding(head []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte("<\x00")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}), bytes.HasPrefix(head, []byte("\x00<")):
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	default:
+		return nil
+	}
+}
+
+/*
+openZip opens FB2 file in ZIP archive selected by options, by default it is
+the first FB2 file. If r supports random access and its size is known(passed
+in size or r has method Size, e.g. bytes.Reader) the archive is read
+directly, otherwise the buffered stream br is loaded into memory first
+*/
+func openZip(r io.Reader, br *bufio.Reader, size int64, opt option) (*bookReader, error) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok || size <= 0 {
+		var buf bytes.Buffer
+		if size > 0 {
+			buf.Grow(int(size))
+		}
+		if _, err := buf.ReadFrom(br); err != nil {
+			return nil, err
+		}
+		ra, size = bytes.NewReader(buf.Bytes()), int64(buf.Len())
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+
+	books := fb2Entries(zr)
+	if opt.zipEntry != "" {
+		for _, f := range books {
+			if f.Name == opt.zipEntry {
+				return openZipEntry(f)
+			}
+		}
+		return nil, fmt.Errorf("%w: file %q not found", ErrNoFB2InZip, opt.zipEntry)
+	}
+
+	if len(books) == 0 {
+		return nil, ErrNoFB2InZip
+	}
+	if opt.zipEntryIndex < 0 || opt.zipEntryIndex >= len(books) {
+		return nil, fmt.Errorf("%w: file index %d is out of range, the archive contains %d FB2 files",
+			ErrNoFB2InZip, opt.zipEntryIndex, len(books))
+	}
+
+	return openZipEntry(books[opt.zipEntryIndex])
+}
+
+func openZipEntry(f *zip.File) (*bookReader, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &bookReader{ReadCloser: rc, size: int64(f.UncompressedSize64)}, nil
+}
+
+// fb2Entries returns FB2 files of the archive in the archive order
+func fb2Entries(zr *zip.Reader) []*zip.File {
+	books := make([]*zip.File, 0, 1)
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".fb2") {
+			books = append(books, f)
+		}
+	}
+
+	return books
+}
+
+/*
+ListZipEntries returns names of all FB2 files in ZIP archive fileName in the
+archive order. The names can be passed to option WithZipEntry and their
+indexes to option WithZipEntryIndex
+*/
+func ListZipEntries(fileName string) ([]string, error) {
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	books := fb2Entries(&zr.Reader)
+	names := make([]string, 0, len(books))
+	for _, f := range books {
+		names = append(names, f.Name)
+	}
+
+	return names, nil
+}
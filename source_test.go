@@ -0,0 +1,63 @@
+package fb2text
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// zipBooks returns a ZIP archive with two FB2 books and a text file
+func zipBooks(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct{ name, content string }{
+		{"readme.txt", "not a book"},
+		{"a.fb2", titleBook("A")},
+		{"dir/b.fb2", titleBook("B")},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestZipEntry(t *testing.T) {
+	data := zipBooks(t)
+	tests := []struct {
+		name  string
+		opts  []Option
+		title string
+		err   error
+	}{
+		{name: "first book", title: "A"},
+		{name: "by name", opts: []Option{WithZipEntry("dir/b.fb2")}, title: "B"},
+		{name: "by index", opts: []Option{WithZipEntryIndex(1)}, title: "B"},
+		{name: "missing name", opts: []Option{WithZipEntry("readme.txt")}, err: ErrNoFB2InZip},
+		{name: "index out of range", opts: []Option{WithZipEntryIndex(2)}, err: ErrNoFB2InZip},
+		{name: "negative index", opts: []Option{WithZipEntryIndex(-1)}, err: ErrInvalidOption},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, _, err := ParseBookBytes(data, tt.opts...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if info.Title != tt.title {
+				t.Errorf("title = %q, want %q", info.Title, tt.title)
+			}
+		})
+	}
+}
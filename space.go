@@ -0,0 +1,46 @@
+package fb2text
+
+import (
+	"strings"
+	"unicode"
+)
+
+/*
+collapseSpace replaces every run of whitespace in s with a single space.
+Whitespace is any Unicode white space: tabs, line and paragraph separators,
+wide spaces, etc, except non-breaking spaces, which are kept. If prev ends
+with a space, the leading whitespace of s is removed, so the spaces are
+collapsed across the parts of a line
+*/
+func collapseSpace(prev, s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	space := prev == "" || strings.HasSuffix(prev, " ")
+	for _, r := range s {
+		if isBreakingSpace(r) {
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		sb.WriteRune(r)
+		space = false
+	}
+
+	return sb.String()
+}
+
+/*
+isBreakingSpace returns true if r is a white space that can be collapsed or
+replaced with a line break. Non-breaking spaces(U+00A0, U+2007, U+202F)
+between initials, numbers and units, etc. are not
+*/
+func isBreakingSpace(r rune) bool {
+	switch r {
+	case '\u00a0', '\u2007', '\u202f':
+		return false
+	}
+
+	return unicode.IsSpace(r)
+}
@@ -0,0 +1,97 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+/*
+Table is a table of the book text. Cells spanning several columns or rows
+are stored once, in the row and the column where they start
+*/
+type Table struct {
+	ID   string     `json:"id"`
+	Rows []TableRow `json:"rows"`
+}
+
+// TableRow is a row of a table. Align is the horizontal alignment of the row cells
+type TableRow struct {
+	Align string      `json:"align"`
+	Cells []TableCell `json:"cells"`
+}
+
+/*
+TableCell is a cell of a table row. Header is true for <th> cells. ColSpan
+and RowSpan are the numbers of columns and rows the cell spans, they are at
+least 1. Align(left, right, center) and VAlign(top, middle, bottom) are the
+cell alignment, the row alignment is used if the cell does not set it
+*/
+type TableCell struct {
+	Header  bool   `json:"header"`
+	Text    string `json:"text"`
+	Spans   []Span `json:"spans"`
+	ColSpan int    `json:"colSpan"`
+	RowSpan int    `json:"rowSpan"`
+	Align   string `json:"align"`
+	VAlign  string `json:"vAlign"`
+}
+
+// tableStart handles the start of a table element in the book text
+func (p *parser) tableStart(se xml.StartElement) {
+	switch se.Name.Local {
+	case "table":
+		p.table = &Table{ID: attrValue(se, "id")}
+	case "tr":
+		if p.table != nil {
+			p.table.Rows = append(p.table.Rows, TableRow{Align: attrValue(se, "align")})
+		}
+	case "th", "td":
+		p.cell = TableCell{
+			Header:  se.Name.Local == "th",
+			ColSpan: spanAttr(se, "colspan"),
+			RowSpan: spanAttr(se, "rowspan"),
+			Align:   attrValue(se, "align"),
+			VAlign:  attrValue(se, "valign"),
+		}
+	}
+}
+
+/*
+tableEnd handles the end of a table element in the book text. It is called
+before the line of a cell is emitted
+*/
+func (p *parser) tableEnd(name string) {
+	t := p.table
+	if t == nil {
+		return
+	}
+
+	switch name {
+	case "table":
+		p.table = nil
+		p.visitTable(*t)
+	case "th", "td":
+		if len(t.Rows) == 0 {
+			// a cell outside rows
+			t.Rows = append(t.Rows, TableRow{})
+		}
+		row := &t.Rows[len(t.Rows)-1]
+		cell := p.cell
+		line := p.line()
+		cell.Text, cell.Spans = line.Text, line.Spans
+		if cell.Align == "" {
+			cell.Align = row.Align
+		}
+		row.Cells = append(row.Cells, cell)
+	}
+}
+
+// spanAttr returns the value of colspan or rowspan attribute, it is 1 if the value is not valid
+func spanAttr(se xml.StartElement, name string) int {
+	n, err := strconv.Atoi(attrValue(se, name))
+	if err != nil || n < 1 {
+		return 1
+	}
+
+	return n
+}
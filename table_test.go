@@ -0,0 +1,77 @@
+package fb2text
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tableBook = `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>T</book-title></title-info></description>
+<body><section><table id="t1"><tr align="right"><th colspan="2">Head</th></tr>
+<tr><td rowspan="2" valign="middle">A</td><td align="center"><emphasis>b</emphasis></td></tr>
+<tr><td>c</td></tr></table>
+</section></body>
+</FictionBook>`
+
+// parseTables returns the tables of the book reported to Visitor.OnTable
+func parseTables(t *testing.T, book string) []Table {
+	t.Helper()
+
+	var tables []Table
+	_, err := VisitBookFromReader(strings.NewReader(book), Visitor{
+		OnTable: func(table Table) { tables = append(tables, table) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tables
+}
+
+func TestTable(t *testing.T) {
+	want := Table{ID: "t1", Rows: []TableRow{
+		{Align: "right", Cells: []TableCell{
+			{Header: true, Text: "Head", ColSpan: 2, RowSpan: 1, Align: "right"},
+		}},
+		{Cells: []TableCell{
+			{Text: "A", ColSpan: 1, RowSpan: 2, VAlign: "middle"},
+			{Text: "b", Spans: []Span{{Start: 0, End: 1, Style: StyleEmphasis}}, ColSpan: 1, RowSpan: 1, Align: "center"},
+		}},
+		{Cells: []TableCell{
+			{Text: "c", ColSpan: 1, RowSpan: 1},
+		}},
+	}}
+
+	tables := parseTables(t, tableBook)
+	if len(tables) != 1 || !reflect.DeepEqual(tables[0], want) {
+		t.Errorf("tables = %+v, want %+v", tables, want)
+	}
+
+	// the cells are still returned as paragraphs
+	_, lines, err := ParseBookFromReader(strings.NewReader(tableBook), ParseBody())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := []string{"{{section}}", "Head", "A", "{{emon}}b{{emoff}}", "c"}; !reflect.DeepEqual(lines, text) {
+		t.Errorf("lines = %q, want %q", lines, text)
+	}
+}
+
+func TestTableJSON(t *testing.T) {
+	table := Table{ID: "t1", Rows: []TableRow{{Align: "left", Cells: []TableCell{
+		{Header: true, Text: "a", ColSpan: 2, RowSpan: 1, Align: "left", VAlign: "top"},
+	}}}}
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"id":"t1","rows":[{"align":"left","cells":[{"header":true,"text":"a","spans":null,` +
+		`"colSpan":2,"rowSpan":1,"align":"left","vAlign":"top"}]}]}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,348 @@
+package fb2text
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TableStyle is a style of tables rendered as text, see Table.Render
+type TableStyle int
+
+const (
+	// TableBox draws the cell borders with box-drawing characters
+	TableBox TableStyle = iota
+	// TablePlain separates the columns with spaces, borders are not drawn
+	TablePlain
+)
+
+// box-drawing line directions of a canvas position
+const (
+	lineUp = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+)
+
+// boxChars are the box-drawing characters by the line directions
+var boxChars = map[int]rune{
+	lineLeft | lineRight:                     '─',
+	lineLeft:                                 '─',
+	lineRight:                                '─',
+	lineUp | lineDown:                        '│',
+	lineUp:                                   '│',
+	lineDown:                                 '│',
+	lineDown | lineRight:                     '┌',
+	lineDown | lineLeft:                      '┐',
+	lineUp | lineRight:                       '└',
+	lineUp | lineLeft:                        '┘',
+	lineUp | lineDown | lineRight:            '├',
+	lineUp | lineDown | lineLeft:             '┤',
+	lineDown | lineLeft | lineRight:          '┬',
+	lineUp | lineLeft | lineRight:            '┴',
+	lineUp | lineDown | lineLeft | lineRight: '┼',
+}
+
+// placedCell is a table cell with its position in the table grid
+type placedCell struct {
+	cell     TableCell
+	row, col int
+	lines    []string
+}
+
+/*
+Render renders the table as monospace text not wider than maxWidth
+characters. Column widths are calculated from the cell text, when the table
+does not fit into maxWidth the widest columns are narrowed and the cell
+text is wrapped. Columns are at least one character wide, so a table with a
+lot of columns can be wider than maxWidth. Zero maxWidth means no limit.
+The cell alignment and colspan/rowspan are taken into account, emphasis is
+not shown. The width is counted in runes, so wide characters(e.g. CJK) can
+break the layout
+*/
+func (t Table) Render(maxWidth int, style TableStyle) []string {
+	cells, cols, rows := t.place()
+	if cols == 0 {
+		return nil
+	}
+
+	// the separator between columns and rows and the total overhead
+	colSep, rowSep, overhead := 2, 0, 2*(cols-1)
+	if style == TableBox {
+		colSep, rowSep, overhead = 3, 1, 3*cols+1
+	}
+
+	widths := columnWidths(cells, cols, colSep)
+	if maxWidth > 0 {
+		fitWidths(widths, maxWidth-overhead)
+	}
+
+	colX := make([]int, cols+1)
+	for i, w := range widths {
+		colX[i+1] = colX[i] + w + colSep
+	}
+	contentWidth := func(pc *placedCell) int {
+		return colX[pc.col+pc.cell.ColSpan] - colX[pc.col] - colSep
+	}
+
+	heights := make([]int, rows)
+	for i := range cells {
+		pc := &cells[i]
+		pc.lines = wrapText(pc.cell.Text, contentWidth(pc))
+		if pc.cell.RowSpan == 1 && len(pc.lines) > heights[pc.row] {
+			heights[pc.row] = len(pc.lines)
+		}
+	}
+	for _, pc := range cells {
+		last := pc.row + pc.cell.RowSpan - 1
+		h := (pc.cell.RowSpan - 1) * rowSep
+		for r := pc.row; r <= last; r++ {
+			h += heights[r]
+		}
+		if h < len(pc.lines) {
+			heights[last] += len(pc.lines) - h
+		}
+	}
+
+	rowY := make([]int, rows+1)
+	for i, h := range heights {
+		rowY[i+1] = rowY[i] + h + rowSep
+	}
+
+	canvas := newTextCanvas(colX[cols]+rowSep, rowY[rows]+rowSep)
+	for _, pc := range cells {
+		x0, x1 := colX[pc.col], colX[pc.col+pc.cell.ColSpan]
+		y0, y1 := rowY[pc.row], rowY[pc.row+pc.cell.RowSpan]
+		width, height := contentWidth(&pc), y1-y0-rowSep
+		if style == TableBox {
+			canvas.box(x0, y0, x1, y1)
+			x0, y0 = x0+2, y0+1
+		}
+
+		top := 0
+		switch pc.cell.VAlign {
+		case "middle":
+			top = (height - len(pc.lines)) / 2
+		case "bottom":
+			top = height - len(pc.lines)
+		}
+		for i, line := range pc.lines {
+			left := 0
+			switch pc.cell.Align {
+			case "center":
+				left = (width - utf8.RuneCountInString(line)) / 2
+			case "right":
+				left = width - utf8.RuneCountInString(line)
+			}
+			canvas.text(x0+left, y0+top+i, line)
+		}
+	}
+
+	return canvas.lines()
+}
+
+/*
+place puts the cells into the table grid taking into account the cells that
+span several rows. Empty cells are added to the rows shorter than the table,
+so every grid position is covered by a cell. Returns the cells and the
+numbers of columns and rows
+*/
+func (t Table) place() ([]placedCell, int, int) {
+	var cells []placedCell
+	// taken are the grid positions covered by the cells
+	var taken [][]bool
+	take := func(row, col int) {
+		for len(taken) <= row {
+			taken = append(taken, nil)
+		}
+		for len(taken[row]) <= col {
+			taken[row] = append(taken[row], false)
+		}
+		taken[row][col] = true
+	}
+	isTaken := func(row, col int) bool {
+		return row < len(taken) && col < len(taken[row]) && taken[row][col]
+	}
+
+	cols := 0
+	for r, row := range t.Rows {
+		col := 0
+		for _, cell := range row.Cells {
+			for isTaken(r, col) {
+				col++
+			}
+			cell.RowSpan = min(max(cell.RowSpan, 1), len(t.Rows)-r)
+			cell.ColSpan = max(cell.ColSpan, 1)
+			cells = append(cells, placedCell{cell: cell, row: r, col: col})
+			for i := r; i < r+cell.RowSpan; i++ {
+				for j := col; j < col+cell.ColSpan; j++ {
+					take(i, j)
+				}
+			}
+			col += cell.ColSpan
+		}
+		cols = max(cols, col)
+	}
+	for r := range taken {
+		cols = max(cols, len(taken[r]))
+	}
+
+	for r := range t.Rows {
+		for c := 0; c < cols; c++ {
+			if !isTaken(r, c) {
+				cells = append(cells, placedCell{cell: TableCell{ColSpan: 1, RowSpan: 1}, row: r, col: c})
+			}
+		}
+	}
+
+	return cells, cols, len(t.Rows)
+}
+
+/*
+columnWidths returns the widths of the columns needed to show the cells
+without wrapping. The cells spanning several columns widen the spanned
+columns evenly if they do not fit
+*/
+func columnWidths(cells []placedCell, cols, colSep int) []int {
+	widths := make([]int, cols)
+	for i := range widths {
+		widths[i] = 1
+	}
+	for _, pc := range cells {
+		if pc.cell.ColSpan == 1 {
+			widths[pc.col] = max(widths[pc.col], utf8.RuneCountInString(pc.cell.Text))
+		}
+	}
+
+	for _, pc := range cells {
+		span := pc.cell.ColSpan
+		if span == 1 {
+			continue
+		}
+		have := (span - 1) * colSep
+		for _, w := range widths[pc.col : pc.col+span] {
+			have += w
+		}
+		for need := utf8.RuneCountInString(pc.cell.Text) - have; need > 0; {
+			for i := pc.col; i < pc.col+span && need > 0; i++ {
+				widths[i]++
+				need--
+			}
+		}
+	}
+
+	return widths
+}
+
+/*
+fitWidths narrows the widest columns until the sum of widths fits into
+available width. Columns are not narrowed below one character
+*/
+func fitWidths(widths []int, available int) {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	for total > available {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] == 1 {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+/*
+wrapText splits the text into lines not longer than width, long words are
+broken. Words joined with non-breaking spaces are kept on one line if they fit
+*/
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.FieldsFunc(text, isBreakingSpace) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// textCanvas is a rectangle of characters with box-drawing lines
+type textCanvas struct {
+	chars [][]rune
+	dirs  [][]int
+}
+
+func newTextCanvas(width, height int) *textCanvas {
+	c := &textCanvas{chars: make([][]rune, height), dirs: make([][]int, height)}
+	for y := range c.chars {
+		c.chars[y] = []rune(strings.Repeat(" ", width))
+		c.dirs[y] = make([]int, width)
+	}
+
+	return c
+}
+
+// box draws the border of the rectangle from (x0, y0) to (x1, y1)
+func (c *textCanvas) box(x0, y0, x1, y1 int) {
+	for x := x0; x < x1; x++ {
+		c.dirs[y0][x] |= lineRight
+		c.dirs[y0][x+1] |= lineLeft
+		c.dirs[y1][x] |= lineRight
+		c.dirs[y1][x+1] |= lineLeft
+	}
+	for y := y0; y < y1; y++ {
+		c.dirs[y][x0] |= lineDown
+		c.dirs[y+1][x0] |= lineUp
+		c.dirs[y][x1] |= lineDown
+		c.dirs[y+1][x1] |= lineUp
+	}
+}
+
+// text writes the text starting at (x, y)
+func (c *textCanvas) text(x, y int, text string) {
+	for _, r := range text {
+		c.chars[y][x] = r
+		x++
+	}
+}
+
+// lines returns the canvas as text lines without trailing spaces
+func (c *textCanvas) lines() []string {
+	res := make([]string, len(c.chars))
+	for y, row := range c.chars {
+		for x, d := range c.dirs[y] {
+			if d != 0 {
+				row[x] = boxChars[d]
+			}
+		}
+		res[y] = strings.TrimRight(string(row), " ")
+	}
+
+	return res
+}
@@ -0,0 +1,128 @@
+package fb2text
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cell returns a table cell spanning one column and one row
+func cell(text string) TableCell {
+	return TableCell{Text: text, ColSpan: 1, RowSpan: 1}
+}
+
+func TestTableRender(t *testing.T) {
+	header := func(text string) TableCell {
+		c := cell(text)
+		c.Header = true
+		return c
+	}
+	aligned := func(text, align, valign string) TableCell {
+		c := cell(text)
+		c.Align, c.VAlign = align, valign
+		return c
+	}
+	spanned := func(text string, cols, rows int, valign string) TableCell {
+		return TableCell{Text: text, ColSpan: cols, RowSpan: rows, VAlign: valign}
+	}
+
+	alignTable := Table{Rows: []TableRow{
+		{Cells: []TableCell{header("Name"), header("Count")}},
+		{Cells: []TableCell{aligned("a", "center", ""), aligned("1", "right", "")}},
+	}}
+	valignTable := Table{Rows: []TableRow{
+		{Cells: []TableCell{cell("one two three four"), aligned("mid", "", "middle"), aligned("bot", "", "bottom")}},
+	}}
+	spanTable := Table{Rows: []TableRow{
+		{Cells: []TableCell{spanned("wide", 2, 1, ""), spanned("tall", 1, 2, "bottom")}},
+		{Cells: []TableCell{cell("a"), cell("b")}},
+	}}
+
+	tests := []struct {
+		name     string
+		table    Table
+		maxWidth int
+		style    TableStyle
+		want     []string
+	}{
+		{
+			name:  "align box",
+			table: alignTable,
+			style: TableBox,
+			want: []string{
+				"┌──────┬───────┐",
+				"│ Name │ Count │",
+				"├──────┼───────┤",
+				"│  a   │     1 │",
+				"└──────┴───────┘",
+			},
+		},
+		{
+			name:  "align plain",
+			table: alignTable,
+			style: TablePlain,
+			want: []string{
+				"Name  Count",
+				" a        1",
+			},
+		},
+		{
+			name:     "valign box",
+			table:    valignTable,
+			maxWidth: 22,
+			style:    TableBox,
+			want: []string{
+				"┌────────┬─────┬─────┐",
+				"│ one    │     │     │",
+				"│ two    │ mid │     │",
+				"│ three  │     │     │",
+				"│ four   │     │ bot │",
+				"└────────┴─────┴─────┘",
+			},
+		},
+		{
+			name:     "valign plain",
+			table:    valignTable,
+			maxWidth: 22,
+			style:    TablePlain,
+			want: []string{
+				"one two       mid",
+				"three four         bot",
+			},
+		},
+		{
+			name:  "colspan and rowspan box",
+			table: spanTable,
+			style: TableBox,
+			want: []string{
+				"┌───────┬──────┐",
+				"│ wide  │      │",
+				"├───┬───┤      │",
+				"│ a │ b │ tall │",
+				"└───┴───┴──────┘",
+			},
+		},
+		{
+			name:  "colspan and rowspan plain",
+			table: spanTable,
+			style: TablePlain,
+			want: []string{
+				"wide",
+				"a  b  tall",
+			},
+		},
+		{
+			name:  "empty table",
+			table: Table{},
+			style: TableBox,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.table.Render(tt.maxWidth, tt.style)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Render() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
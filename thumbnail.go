@@ -0,0 +1,106 @@
+package fb2text
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	// FB2 books may contain GIF images besides JPEG and PNG
+	_ "image/gif"
+)
+
+// ThumbnailFormat is the image format of a thumbnail
+type ThumbnailFormat int
+
+// Thumbnail formats
+const (
+	ThumbnailJPEG ThumbnailFormat = iota // JPEG with quality 85
+	ThumbnailPNG                         // lossless PNG
+)
+
+// Decode decodes the cover image. JPEG, PNG, and GIF images are supported
+func (c Cover) Decode() (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(c.Data))
+	if err != nil {
+		return nil, fmt.Errorf("fb2text: cannot decode cover %q: %w", c.ID, err)
+	}
+
+	return img, nil
+}
+
+/*
+Thumbnail decodes the cover and scales it down to fit maxWidth x maxHeight
+keeping the aspect ratio. Images that already fit are not enlarged. Returns
+the thumbnail encoded in the given format
+*/
+func (c Cover) Thumbnail(maxWidth, maxHeight int, format ThumbnailFormat) ([]byte, error) {
+	if maxWidth <= 0 || maxHeight <= 0 {
+		return nil, fmt.Errorf("%w: thumbnail size %dx%d", ErrInvalidOption, maxWidth, maxHeight)
+	}
+
+	img, err := c.Decode()
+	if err != nil {
+		return nil, err
+	}
+	thumb := ResizeImage(img, maxWidth, maxHeight)
+
+	var buf bytes.Buffer
+	switch format {
+	case ThumbnailJPEG:
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+	case ThumbnailPNG:
+		err = png.Encode(&buf, thumb)
+	default:
+		return nil, fmt.Errorf("%w: unknown thumbnail format %d", ErrInvalidOption, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+/*
+ResizeImage scales the image down to fit maxWidth x maxHeight keeping the
+aspect ratio. Every pixel of the result is the average of the source pixels
+it covers. The image is returned as is if it already fits, if it is empty,
+or if maxWidth or maxHeight is not positive
+*/
+func ResizeImage(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw <= maxWidth && sh <= maxHeight || sw <= 0 || sh <= 0 || maxWidth <= 0 || maxHeight <= 0 {
+		return img
+	}
+
+	dw, dh := maxWidth, sh*maxWidth/sw
+	if dh > maxHeight {
+		dw, dh = sw*maxHeight/sh, maxHeight
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*sh/dh, b.Min.Y+max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*sw/dw, b.Min.X+max((x+1)*sw/dw, x*sw/dw+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,82 @@
+package fb2text
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestResizeImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxW, maxH    int
+		wantW, wantH  int
+	}{
+		{"fits", 10, 20, 10, 20, 10, 20},
+		{"width", 100, 50, 10, 10, 10, 5},
+		{"height", 50, 100, 10, 10, 5, 10},
+		{"thin line", 1000, 1, 10, 10, 10, 1},
+		{"empty image", 0, 0, 10, 10, 0, 0},
+		{"empty image with zero size", 0, 0, 0, 0, 0, 0},
+		{"empty width", 0, 100, 10, 10, 0, 100},
+		{"zero size", 100, 100, 0, 0, 100, 100},
+		{"negative size", 100, 100, -1, 10, 100, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			b := ResizeImage(img, tt.maxW, tt.maxH).Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestResizeImageAverage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	r, g, b, a := ResizeImage(img, 1, 1).At(0, 0).RGBA()
+	if r != 0x7fff || g != 0 || b != 0x7fff || a != 0xffff {
+		t.Errorf("pixel = %x %x %x %x, want the average of red and blue", r, g, b, a)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	cover := Cover{ID: "cover.png", ContentType: "image/png", Data: buf.Bytes()}
+
+	for _, format := range []ThumbnailFormat{ThumbnailJPEG, ThumbnailPNG} {
+		data, err := cover.Thumbnail(10, 10, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != 10 || b.Dy() != 5 {
+			t.Errorf("format %d: size = %dx%d, want 10x5", format, b.Dx(), b.Dy())
+		}
+	}
+
+	if _, err := cover.Thumbnail(0, 10, ThumbnailPNG); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("zero size: error = %v, want %v", err, ErrInvalidOption)
+	}
+	if _, err := cover.Thumbnail(10, 10, ThumbnailFormat(9)); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("unknown format: error = %v, want %v", err, ErrInvalidOption)
+	}
+	if _, err := (Cover{ID: "bad", Data: []byte("not an image")}).Decode(); err == nil {
+		t.Error("decoding a broken image: no error")
+	}
+}
@@ -0,0 +1,50 @@
+package fb2text
+
+import "encoding/xml"
+
+// UnknownElements defines what the parser does with unknown elements of the book text
+type UnknownElements int
+
+const (
+	// UnknownDrop is the default: an unknown element starts a new line and
+	// the text before it is lost
+	UnknownDrop UnknownElements = iota
+	// UnknownText keeps the text of unknown elements: inside a paragraph it
+	// is a part of the paragraph, otherwise it is a separate paragraph
+	UnknownText
+	// UnknownMarker keeps the text of unknown elements as UnknownText does and
+	// marks it with StyleUnknown spans, {{unknown:NAME}} and {{unknownoff}}
+	// in the internal format
+	UnknownMarker
+)
+
+// isUnknown returns true if the element of the book text is not a part of FB2 schema
+func (p *parser) isUnknown(name string) bool {
+	return !knownElements[name] && isInBookContent(p.tags)
+}
+
+/*
+unknownStart starts an unknown element of the book text. An element inside a
+paragraph does not break it, any other element starts a new paragraph
+*/
+func (p *parser) unknownStart(se xml.StartElement) {
+	n := len(p.tags)
+	if n == 0 || !isText(p.tags[n-1]) {
+		p.emitLine()
+	}
+	if p.opt.unknownElements == UnknownMarker {
+		p.openSpan(StyleUnknown, se.Name.Local)
+	}
+}
+
+// unknownEnd ends an unknown element of the book text
+func (p *parser) unknownEnd() {
+	n := len(p.tags)
+	if n == 0 || !isText(p.tags[n-1]) {
+		p.emitLine()
+		return
+	}
+	if p.opt.unknownElements == UnknownMarker {
+		p.closeSpan(StyleUnknown)
+	}
+}
@@ -0,0 +1,46 @@
+package fb2text
+
+import "strings"
+
+// FormatVersion is the version of FB2 specification the book follows
+type FormatVersion string
+
+// FB2 versions
+const (
+	// VersionUnknown means the root element namespace is not FB2 one
+	VersionUnknown FormatVersion = ""
+	// Version20 is FB2 2.0
+	Version20 FormatVersion = "2.0"
+	// Version21 is FB2 2.1 that extends 2.0 with tables, src-title-info,
+	// and output elements. The namespace of 2.1 is the same as of 2.0
+	Version21 FormatVersion = "2.1"
+	// Version30 is FB2 with FB3 namespace and markup
+	Version30 FormatVersion = "3.0"
+)
+
+// elements21 are the elements added in FB2 2.1
+var elements21 = map[string]bool{
+	"table": true, "src-title-info": true, "output": true,
+}
+
+/*
+detectVersion updates the book format version by the element. The version
+is detected by the root element namespace, the elements of newer versions
+raise it
+*/
+func (p *parser) detectVersion(name, space string) {
+	if name == "FictionBook" && len(p.tags) == 0 {
+		space = strings.ToLower(space)
+		switch {
+		case strings.Contains(space, "fictionbook/2.0"):
+			p.info.Version = Version20
+		case strings.Contains(space, "fictionbook3"), strings.Contains(space, "fictionbook/3"):
+			p.info.Version = Version30
+		}
+		return
+	}
+
+	if p.info.Version == Version20 && elements21[name] {
+		p.info.Version = Version21
+	}
+}
@@ -0,0 +1,245 @@
+package fb2text
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+)
+
+/*
+Span is a range of styled text in a line. Start and End are byte offsets
+in the line text, End is exclusive. Style is StyleEmphasis or StyleStrong
+for emphasized text, Target is the id of the note or the element for StyleNote and
+StyleAnchor, the URL for StyleLink, and the id of the binary for StyleImage
+*/
+type Span struct {
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	Style  SpanStyle `json:"style"`
+	Target string    `json:"target,omitempty"`
+}
+
+/*
+Visitor is a set of callbacks called while the book is parsed. It gives
+access to the book structure without going through the internal string
+format: text passed to callbacks does not contain any "{{...}}" markers,
+emphasized parts of the text are described by spans instead.
+
+All callbacks are optional, nil callbacks are skipped. The callbacks are
+called in the order the elements appear in the book
+*/
+type Visitor struct {
+	// OnBodyStart and OnBodyEnd are called for every body of the book. name
+	// is the value of the body attribute 'name', it is empty for the main body
+	OnBodyStart func(name string)
+	OnBodyEnd   func()
+
+	// OnSectionStart and OnSectionEnd are called for every section,
+	// nested sections are reported between the calls for the outer one
+	OnSectionStart func()
+	OnSectionEnd   func()
+	// OnSectionID is called after OnSectionStart if the section has an id
+	OnSectionID func(id string)
+
+	// OnBlockStart and OnBlockEnd are called for elements that group
+	// paragraphs: title, epigraph, poem, stanza, cite, table, and annotation.
+	// The lines of the block are reported between the calls
+	OnBlockStart func(name string)
+	OnBlockEnd   func(name string)
+
+	// OnTitle is called for every line of a section, a body, a poem, or a
+	// stanza title
+	OnTitle func(text string, em []Span)
+	// OnEpigraph is called for every line of an epigraph
+	OnEpigraph func(text string, em []Span)
+	// OnEpigraphAuthor is called for the author of an epigraph
+	OnEpigraphAuthor func(text string, em []Span)
+	// OnCiteAuthor is called for the author of a block quotation(cite)
+	OnCiteAuthor func(text string, em []Span)
+	// OnPoemAuthor is called for the author of a poem
+	OnPoemAuthor func(text string, em []Span)
+	// OnStanzaSubtitle is called for the subtitle of a stanza
+	OnStanzaSubtitle func(text string, em []Span)
+	// OnParagraph is called for every regular paragraph of text and for the
+	// lines without a callback of their kind, e.g. verses, subtitles, or code
+	OnParagraph func(text string, em []Span)
+	// OnLine is called for every line of text before the callback of its
+	// kind, e.g. OnParagraph. Unlike them it gets the line kind, so verses,
+	// subtitles, dates, or code can be told from paragraphs
+	OnLine func(line Line)
+	// OnEmptyLine is called for every <empty-line/>
+	OnEmptyLine func()
+
+	// OnEmphasis is called for every emphasized or strong fragment of text,
+	// other styled fragments(e.g, note references) are not reported.
+	// It is called before the callback for the line containing the fragment
+	OnEmphasis func(text string)
+
+	// OnTable is called for every table after its cells are reported as
+	// paragraphs, before OnBlockEnd of the table
+	OnTable func(t Table)
+
+	// OnImage is called for every image outside paragraphs, id is the id of
+	// the image binary. Images inside paragraphs are image spans of the line
+	OnImage func(id, alt string)
+	// OnImageCaption is called after OnImage if the image has a title
+	OnImageCaption func(text string)
+
+	// OnBinary is called for every binary attachment(e.g, image) of the
+	// book with its decoded content
+	OnBinary func(id, contentType string, data []byte)
+
+	// OnLanguage is called when the language of the text changes: before
+	// the callbacks for an element with xml:lang attribute and after the end
+	// of the element with the language of its parent. lang is empty if the
+	// language is not set
+	OnLanguage func(lang string)
+}
+
+/*
+VisitBook parses the book fileName and calls the visitor callbacks for the
+book elements. The book body is always parsed, so option ParseBody is not
+required. Returns information about the book and the first error that
+stopped parsing
+*/
+func VisitBook(fileName string, v Visitor, opts ...Option) (BookInfo, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return BookInfo{}, err
+	}
+	defer file.Close()
+
+	if fi, err := file.Stat(); err == nil {
+		opts = append([]Option{SizeHint(fi.Size())}, opts...)
+	}
+
+	return VisitBookFromReader(file, v, opts...)
+}
+
+/*
+VisitBookFromReader works the same way as VisitBook but reads the book from
+r. The stream can be a raw FB2, ZIP or GZIP archive
+*/
+func VisitBookFromReader(r io.Reader, v Visitor, opts ...Option) (BookInfo, error) {
+	opt, err := newOption(opts)
+	if err != nil {
+		return BookInfo{}, err
+	}
+	opt.parseBody = true
+
+	book, err := openBook(r, opt)
+	if err != nil {
+		return BookInfo{}, err
+	}
+	defer book.Close()
+
+	p := newParser(book, opt)
+	p.visitor = &v
+	for p.step() {
+		// the visitor gets everything, the lines are not needed
+		p.lines = p.lines[:0]
+	}
+
+	return p.info, p.err
+}
+
+func (p *parser) visitLine(line Line) {
+	if p.visitor == nil {
+		return
+	}
+
+	if p.visitor.OnLine != nil {
+		p.visitor.OnLine(line)
+	}
+	if line.Kind == KindCaption {
+		if p.visitor.OnImageCaption != nil {
+			p.visitor.OnImageCaption(line.Text)
+		}
+		return
+	}
+
+	var fn func(string, []Span)
+	switch line.Kind {
+	case KindTitle, KindPoemTitle, KindStanzaTitle:
+		fn = p.visitor.OnTitle
+	case KindEpigraph:
+		fn = p.visitor.OnEpigraph
+	case KindEpigraphAuthor:
+		fn = p.visitor.OnEpigraphAuthor
+	case KindCiteAuthor:
+		fn = p.visitor.OnCiteAuthor
+	case KindPoemAuthor:
+		fn = p.visitor.OnPoemAuthor
+	case KindStanzaSubtitle:
+		fn = p.visitor.OnStanzaSubtitle
+	default:
+		fn = p.visitor.OnParagraph
+	}
+
+	if fn != nil {
+		fn(line.Text, line.Spans)
+	}
+}
+
+func (p *parser) visitBodyStart(se xml.StartElement) {
+	if p.visitor == nil || p.visitor.OnBodyStart == nil {
+		return
+	}
+
+	name := ""
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "name" {
+			name = attr.Value
+		}
+	}
+	p.visitor.OnBodyStart(name)
+}
+
+func (p *parser) visitBlockStart(name string) {
+	if p.visitor != nil && p.visitor.OnBlockStart != nil {
+		p.visitor.OnBlockStart(name)
+	}
+}
+
+func (p *parser) visitBlockEnd(name string) {
+	if p.visitor != nil && p.visitor.OnBlockEnd != nil {
+		p.visitor.OnBlockEnd(name)
+	}
+}
+
+func (p *parser) visitEmptyLine() {
+	if p.visitor != nil && p.visitor.OnEmptyLine != nil {
+		p.visitor.OnEmptyLine()
+	}
+}
+
+// visitEmphasis reports the emphasized fragment started at start that has just been closed
+func (p *parser) visitEmphasis(start int) {
+	if p.visitor != nil && p.visitor.OnEmphasis != nil {
+		p.visitor.OnEmphasis(p.currLine[start:])
+	}
+}
+
+func (p *parser) visitLanguage(lang string) {
+	if p.visitor != nil && p.visitor.OnLanguage != nil {
+		p.visitor.OnLanguage(lang)
+	}
+}
+
+func (p *parser) visitTable(t Table) {
+	if p.visitor != nil && p.visitor.OnTable != nil {
+		p.visitor.OnTable(t)
+	}
+}
+
+func (p *parser) visitImage(id, alt string) {
+	if p.visitor != nil && p.visitor.OnImage != nil {
+		p.visitor.OnImage(id, alt)
+	}
+}
+
+func (p *parser) visitBinary(data []byte) {
+	if p.visitor != nil && p.visitor.OnBinary != nil {
+		p.visitor.OnBinary(p.binaryID, p.binaryCType, data)
+	}
+}
@@ -0,0 +1,69 @@
+package fb2text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVisitBook(t *testing.T) {
+	var events []string
+	add := func(format string) func(text string, em []Span) {
+		return func(text string, em []Span) {
+			// the spans are shown as the text fragments they cover
+			event := format + text
+			for _, span := range em {
+				event += " [" + text[span.Start:span.End] + "]"
+			}
+			events = append(events, event)
+		}
+	}
+	v := Visitor{
+		OnBodyStart:      func(name string) { events = append(events, "body "+name) },
+		OnBodyEnd:        func() { events = append(events, "/body") },
+		OnSectionStart:   func() { events = append(events, "section") },
+		OnSectionEnd:     func() { events = append(events, "/section") },
+		OnSectionID:      func(id string) { events = append(events, "id "+id) },
+		OnBlockStart:     func(name string) { events = append(events, name) },
+		OnBlockEnd:       func(name string) { events = append(events, "/"+name) },
+		OnTitle:          add("title: "),
+		OnEpigraph:       add("epigraph: "),
+		OnEpigraphAuthor: add("epigraph author: "),
+		OnParagraph:      add("p: "),
+		OnEmptyLine:      func() { events = append(events, "empty") },
+		OnEmphasis:       func(text string) { events = append(events, "em: "+text) },
+	}
+
+	info, err := VisitBookFromReader(strings.NewReader(notesBook), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Notes" {
+		t.Errorf("title = %q, want %q", info.Title, "Notes")
+	}
+
+	want := []string{
+		"body ",
+		"title", "title: Book", "/title",
+		"section", "id ch1",
+		"title", "title: One", "/title",
+		"epigraph", "epigraph: Epi", "epigraph author: Author", "/epigraph",
+		"em: big",
+		"p: A big word1 here. [big] [1]",
+		"em: bold",
+		"p: Code {{x}} bold [bold]",
+		"empty",
+		"section", "p: Inner", "/section",
+		"/section",
+		"/body",
+		"body notes",
+		"section", "id n1",
+		"title", "title: 1", "/title",
+		"p: Note text.",
+		"/section",
+		"/body",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events =\n%q\nwant\n%q", events, want)
+	}
+}
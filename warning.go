@@ -0,0 +1,52 @@
+package fb2text
+
+import "fmt"
+
+/*
+Warning is a non-fatal defect of a book found during parsing. The book is
+still parsed, but it may need a repair
+*/
+type Warning struct {
+	// Line and Column are the position in the FB2 XML where the defect is found
+	Line   int
+	Column int
+	// Offset is the byte offset in the FB2 XML
+	Offset  int64
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", w.Line, w.Column, w.Message)
+}
+
+// knownElements contains all elements of FB2 2.x schema
+var knownElements = map[string]bool{
+	"FictionBook": true, "stylesheet": true, "description": true, "body": true, "binary": true,
+	"title-info": true, "src-title-info": true, "document-info": true, "publish-info": true,
+	"custom-info": true, "output": true, "part": true, "output-document-class": true,
+	"genre": true, "author": true, "first-name": true, "middle-name": true, "last-name": true,
+	"nickname": true, "home-page": true, "email": true, "id": true, "book-title": true,
+	"annotation": true, "keywords": true, "date": true, "coverpage": true, "image": true,
+	"lang": true, "src-lang": true, "translator": true, "sequence": true, "program-used": true,
+	"src-url": true, "src-ocr": true, "version": true, "history": true, "book-name": true,
+	"publisher": true, "city": true, "year": true, "isbn": true,
+	"title": true, "epigraph": true, "section": true, "p": true, "poem": true, "stanza": true,
+	"v": true, "cite": true, "subtitle": true, "empty-line": true, "text-author": true,
+	"table": true, "tr": true, "th": true, "td": true, "strong": true, "emphasis": true,
+	"style": true, "a": true, "strikethrough": true, "sub": true, "sup": true, "code": true,
+}
+
+// warn reports a warning at the current decoder position
+func (p *parser) warn(format string, args ...any) {
+	if p.opt.warnings == nil {
+		return
+	}
+
+	line, col := p.decoder.InputPos()
+	p.opt.warnings(Warning{
+		Line:    line,
+		Column:  col,
+		Offset:  p.decoder.InputOffset(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}